@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/marcusziade/songlink-cli.git/pkg/songlink"
+)
+
+// withAlbum returns the responses to output for -album and -album-also: the album of the song
+// response resolved to, in place of the song or after it. Albums are returned as they are.
+func withAlbum(ctx context.Context, client *songlink.Client, response *songlink.Response, country string) ([]*songlink.Response, error) {
+	if *albumFlag && *albumAlsoFlag {
+		return nil, errors.New("use either -album or -album-also")
+	}
+	if !*albumFlag && !*albumAlsoFlag {
+		return []*songlink.Response{response}, nil
+	}
+	if entity := response.Entity(); entity != nil && entity.Type == "album" {
+		return []*songlink.Response{response}, nil
+	}
+
+	query, err := albumQuery(ctx, response)
+	if err != nil {
+		return nil, err
+	}
+	query.Country = country
+	albumResponse, err := fetchLinks(ctx, client, query)
+	if err != nil {
+		return nil, fmt.Errorf("error resolving the album: %w", err)
+	}
+
+	if *albumFlag {
+		return []*songlink.Response{albumResponse}, nil
+	}
+	return []*songlink.Response{response, albumResponse}, nil
+}
+
+// albumQuery returns the query resolving the album of the song response resolved to. The album ID is
+// taken from the Apple Music link when it has one, otherwise the song is looked up in the Apple Music catalog.
+func albumQuery(ctx context.Context, response *songlink.Response) (songlink.Query, error) {
+	if query, ok := response.AlbumQuery(); ok {
+		return query, nil
+	}
+
+	songID := appleMusicSongID(response)
+	if songID == "" {
+		return songlink.Query{}, errors.New("couldn't find the album: song.link has no Apple Music link for the song")
+	}
+	config, err := LoadConfig()
+	if err != nil {
+		return songlink.Query{}, fmt.Errorf("error loading config: %w", err)
+	}
+	if !config.HasAppleMusicCredentials() {
+		return songlink.Query{}, errors.New("couldn't find the album in the song.link response; run 'songlink-cli config' to set up Apple Music credentials for catalog lookups")
+	}
+	searcher, err := newSearchClient(config)
+	if err != nil {
+		return songlink.Query{}, fmt.Errorf("error creating music searcher: %w", err)
+	}
+	storefront, err := configuredStorefront()
+	if err != nil {
+		return songlink.Query{}, err
+	}
+
+	album, err := searcher.LookupSongAlbum(ctx, songID, storefront)
+	if err != nil {
+		return songlink.Query{}, err
+	}
+	return songlink.Query{Platform: "appleMusic", Type: "album", ID: album.ID}, nil
+}
+
+// appleMusicSongID returns the Apple Music ID of the song in response, or an empty string if it has none
+func appleMusicSongID(response *songlink.Response) string {
+	for _, entity := range response.EntitiesByUniqueID {
+		if entity.APIProvider == "itunes" && entity.Type == "song" {
+			return entity.ID
+		}
+	}
+	return ""
+}
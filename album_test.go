@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/marcusziade/songlink-cli.git/pkg/songlink"
+)
+
+func TestWithAlbum(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if query.Get("platform") != "appleMusic" || query.Get("type") != "album" || query.Get("id") != "1572919347" {
+			t.Errorf("unexpected album query: %s", r.URL.RawQuery)
+		}
+		fmt.Fprintln(w, `{"pageUrl": "https://album.link/i/1572919347", "linksByPlatform": {}}`)
+	}))
+	defer server.Close()
+	client := songlink.NewClient(songlink.WithBaseURL(server.URL))
+
+	song := &songlink.Response{
+		PageURL: "https://song.link/i/1572919354",
+		LinksByPlatform: songlink.LinksByPlatform{
+			"appleMusic": {URL: "https://geo.music.apple.com/us/album/_/1572919347?i=1572919354&mt=1&app=music"},
+		},
+	}
+
+	responses, err := withAlbum(context.Background(), client, song, "")
+	if err != nil || len(responses) != 1 || responses[0] != song {
+		t.Errorf("withAlbum() without -album = %v, %v; want the song", responses, err)
+	}
+
+	*albumAlsoFlag = true
+	defer func() { *albumAlsoFlag = false }()
+	responses, err = withAlbum(context.Background(), client, song, "")
+	if err != nil {
+		t.Fatalf("withAlbum returned an unexpected error: %v", err)
+	}
+	if len(responses) != 2 || responses[0] != song || responses[1].PageURL != "https://album.link/i/1572919347" {
+		t.Errorf("withAlbum() with -album-also = %v; want the song and the album", responses)
+	}
+}
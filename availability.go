@@ -0,0 +1,217 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/marcusziade/songlink-cli.git/pkg/songlink"
+)
+
+// defaultAvailabilityCountries are checked when -countries isn't given
+const defaultAvailabilityCountries = "US,GB,DE,FR,SE,FI,JP,BR,IN,AU"
+
+// Availability is the platform × country matrix for one URL
+type Availability struct {
+	InputURL  string                 `json:"input_url" yaml:"input_url"`
+	Title     string                 `json:"title" yaml:"title"`
+	Artist    string                 `json:"artist" yaml:"artist"`
+	Countries []string               `json:"countries" yaml:"countries"`
+	Platforms []PlatformAvailability `json:"platforms" yaml:"platforms"`
+	// Errors holds the countries that couldn't be resolved
+	Errors map[string]string `json:"errors,omitempty" yaml:"errors,omitempty"`
+}
+
+// PlatformAvailability maps each country to the platform's link there, or an empty string if it isn't available
+type PlatformAvailability struct {
+	Platform string            `json:"platform" yaml:"platform"`
+	Links    map[string]string `json:"links" yaml:"links"`
+}
+
+// executeAvailability handles the availability subcommand
+func executeAvailability(ctx context.Context, args []string) error {
+	availabilityCmd := flag.NewFlagSet("availability", flag.ExitOnError)
+	countriesFlag := availabilityCmd.String("countries", defaultAvailabilityCountries, "Comma-separated two-letter country codes to check")
+	formatFlag := availabilityCmd.String("o", *outputFlag, "Output format: text, json, yaml, csv or tsv")
+
+	if err := availabilityCmd.Parse(args); err != nil {
+		return err
+	}
+
+	format, err := ParseOutputFormat(*formatFlag)
+	if err != nil {
+		return err
+	}
+	var countries []string
+	for _, value := range strings.Split(*countriesFlag, ",") {
+		country, err := songlink.ParseCountry(value)
+		if err != nil {
+			return err
+		}
+		if country != "" {
+			countries = append(countries, country)
+		}
+	}
+	if len(countries) == 0 {
+		return errors.New("at least one country is required")
+	}
+
+	searchURL, err := readInputURL(availabilityCmd.Args())
+	if err != nil {
+		return err
+	}
+	client, err := newSonglinkClient()
+	if err != nil {
+		return err
+	}
+	normalizeCtx, cancel := linkContext(ctx)
+	defer cancel()
+	input, err := client.NormalizeURL(normalizeCtx, searchURL)
+	if err != nil {
+		return err
+	}
+	platforms, err := configuredPlatforms()
+	if err != nil {
+		return err
+	}
+
+	if format == FormatText {
+		fmt.Fprintf(os.Stderr, "Checking %d countries...\n", len(countries))
+	}
+	availability := CheckAvailability(ctx, client, input.URL, countries, platforms)
+	availability.InputURL = searchURL
+	if len(availability.Errors) == len(countries) {
+		return fmt.Errorf("error resolving %s: %s", searchURL, availability.Errors[countries[0]])
+	}
+
+	switch format {
+	case FormatText:
+		return writeAvailabilityTable(os.Stdout, availability)
+	case FormatJSON:
+		return writeJSON(os.Stdout, availability)
+	case FormatYAML:
+		return writeYAML(os.Stdout, availability)
+	case FormatCSV:
+		return writeAvailabilityCSV(os.Stdout, ',', availability)
+	default:
+		return writeAvailabilityCSV(os.Stdout, '\t', availability)
+	}
+}
+
+// CheckAvailability resolves searchURL once per country and collects which platforms have a link in each.
+// Rows are limited to platforms if it isn't nil; otherwise every platform available in some country is listed.
+func CheckAvailability(ctx context.Context, client *songlink.Client, searchURL string, countries, platforms []string) Availability {
+	availability := Availability{InputURL: searchURL, Countries: countries}
+	responses := make(map[string]*songlink.Response)
+
+	for _, country := range countries {
+		response, err := fetchCountryLinks(ctx, client, songlink.Query{URL: searchURL, Country: country})
+		if err != nil {
+			if availability.Errors == nil {
+				availability.Errors = make(map[string]string)
+			}
+			availability.Errors[country] = err.Error()
+			continue
+		}
+		responses[country] = response
+		if entity := response.Entity(); entity != nil && availability.Title == "" {
+			availability.Title = entity.Title
+			availability.Artist = entity.ArtistName
+		}
+	}
+
+	rows := platforms
+	if rows == nil {
+		rows = songlink.Platforms
+	}
+	for _, platform := range rows {
+		row := PlatformAvailability{Platform: platform, Links: make(map[string]string)}
+		found := false
+		for _, country := range countries {
+			response, ok := responses[country]
+			if !ok {
+				continue
+			}
+			row.Links[country] = response.LinksByPlatform[platform].URL
+			found = found || row.Links[country] != ""
+		}
+		if found || platforms != nil {
+			availability.Platforms = append(availability.Platforms, row)
+		}
+	}
+
+	return availability
+}
+
+// fetchCountryLinks resolves query within its own -timeout, so that one slow country doesn't use up the time of the others
+func fetchCountryLinks(ctx context.Context, client *songlink.Client, query songlink.Query) (*songlink.Response, error) {
+	ctx, cancel := linkContext(ctx)
+	defer cancel()
+	return fetchLinks(ctx, client, query)
+}
+
+// writeAvailabilityTable prints the matrix with a ✓ for every country a platform has a link in
+func writeAvailabilityTable(w io.Writer, availability Availability) error {
+	if availability.Title != "" {
+		fmt.Fprintf(w, "%s — %s\n\n", availability.Title, availability.Artist)
+	}
+
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(table, "PLATFORM\t%s\n", strings.Join(availability.Countries, "\t"))
+	for _, row := range availability.Platforms {
+		cells := []string{row.Platform}
+		for _, country := range availability.Countries {
+			url, resolved := row.Links[country]
+			switch {
+			case !resolved:
+				cells = append(cells, "?")
+			case url != "":
+				cells = append(cells, "✓")
+			default:
+				cells = append(cells, "-")
+			}
+		}
+		fmt.Fprintln(table, strings.Join(cells, "\t"))
+	}
+	if err := table.Flush(); err != nil {
+		return err
+	}
+
+	for _, country := range availability.Countries {
+		if message, ok := availability.Errors[country]; ok {
+			fmt.Fprintf(w, "\n? %s could not be checked: %s", country, message)
+		}
+	}
+	if len(availability.Errors) > 0 {
+		fmt.Fprintln(w)
+	}
+	return nil
+}
+
+// writeAvailabilityCSV writes one row per platform with the link for each country
+func writeAvailabilityCSV(w io.Writer, separator rune, availability Availability) error {
+	writer := csv.NewWriter(w)
+	writer.Comma = separator
+
+	if err := writer.Write(append([]string{"platform"}, availability.Countries...)); err != nil {
+		return fmt.Errorf("error writing table header: %w", err)
+	}
+	for _, row := range availability.Platforms {
+		cells := []string{row.Platform}
+		for _, country := range availability.Countries {
+			cells = append(cells, row.Links[country])
+		}
+		if err := writer.Write(cells); err != nil {
+			return fmt.Errorf("error writing table row: %w", err)
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
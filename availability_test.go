@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/marcusziade/songlink-cli.git/pkg/songlink"
+)
+
+func TestCheckAvailability(t *testing.T) {
+	// Tidal is only available in the US, Deezer everywhere but the US, and JP fails
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch country := r.URL.Query().Get("userCountry"); country {
+		case "US":
+			fmt.Fprintln(w, `{"pageUrl": "https://song.link/us/i/1", "linksByPlatform": {"spotify": {"url": "https://open.spotify.com/track/1"}, "tidal": {"url": "https://tidal.com/track/1"}}}`)
+		case "JP":
+			w.WriteHeader(http.StatusBadRequest)
+		default:
+			fmt.Fprintln(w, `{"pageUrl": "https://song.link/i/1", "linksByPlatform": {"spotify": {"url": "https://open.spotify.com/track/1"}, "deezer": {"url": "https://deezer.com/track/1"}}}`)
+		}
+	}))
+	defer server.Close()
+
+	availability := CheckAvailability(context.Background(), songlink.NewClient(songlink.WithBaseURL(server.URL)), "https://open.spotify.com/track/1", []string{"US", "FI", "JP"}, nil)
+
+	if _, ok := availability.Errors["JP"]; !ok || len(availability.Errors) != 1 {
+		t.Errorf("Errors = %v; want only JP", availability.Errors)
+	}
+
+	expected := map[string]map[string]string{
+		"spotify": {"US": "https://open.spotify.com/track/1", "FI": "https://open.spotify.com/track/1"},
+		"deezer":  {"US": "", "FI": "https://deezer.com/track/1"},
+		"tidal":   {"US": "https://tidal.com/track/1", "FI": ""},
+	}
+	if len(availability.Platforms) != len(expected) {
+		t.Fatalf("got %d platform rows; want %d: %+v", len(availability.Platforms), len(expected), availability.Platforms)
+	}
+	for _, row := range availability.Platforms {
+		if fmt.Sprint(row.Links) != fmt.Sprint(expected[row.Platform]) {
+			t.Errorf("%s links = %v; want %v", row.Platform, row.Links, expected[row.Platform])
+		}
+	}
+}
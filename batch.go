@@ -0,0 +1,244 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/marcusziade/songlink-cli.git/pkg/songlink"
+)
+
+// batchEntry is the outcome of resolving one line of batch input
+type batchEntry struct {
+	inputURL    string
+	response    *songlink.Response
+	err         error
+	unsupported bool
+}
+
+// executeBatch handles the batch subcommand
+func executeBatch(ctx context.Context, args []string) error {
+	batchCmd := flag.NewFlagSet("batch", flag.ExitOnError)
+	workersFlag := batchCmd.Int("workers", 4, "Number of URLs to resolve concurrently")
+	formatFlag := batchCmd.String("o", *outputFlag, "Output format: text, json, yaml, csv or tsv")
+
+	if err := batchCmd.Parse(args); err != nil {
+		return err
+	}
+	if *workersFlag < 1 {
+		return fmt.Errorf("-workers must be at least 1")
+	}
+
+	format, err := ParseOutputFormat(*formatFlag)
+	if err != nil {
+		return err
+	}
+
+	// Read URLs from the file argument, or stdin if none (or "-") is given
+	var input io.Reader = os.Stdin
+	if path := batchCmd.Arg(0); path != "" && path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("error opening input file: %w", err)
+		}
+		defer file.Close()
+		input = file
+	}
+	urls, err := readBatchURLs(input)
+	if err != nil {
+		return err
+	}
+	if len(urls) == 0 {
+		return errors.New("no URLs to resolve")
+	}
+
+	client, err := newSonglinkClient()
+	if err != nil {
+		return err
+	}
+	country, err := configuredCountry()
+	if err != nil {
+		return err
+	}
+
+	entries := resolveBatch(ctx, client, country, urls, *workersFlag)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	// The summary is printed even when -strict rejected entries
+	err = writeBatch(os.Stdout, format, entries)
+	printBatchSummary(os.Stderr, entries)
+	return err
+}
+
+// readBatchURLs reads one URL per line, skipping blank lines and lines starting with #
+func readBatchURLs(r io.Reader) ([]string, error) {
+	var urls []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		urls = append(urls, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading input: %w", err)
+	}
+	return urls, nil
+}
+
+// resolveBatch resolves urls with a pool of workers. Entries are returned in input order.
+func resolveBatch(ctx context.Context, client *songlink.Client, country string, urls []string, workers int) []batchEntry {
+	entries := make([]batchEntry, len(urls))
+	jobs := make(chan int)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range jobs {
+				entries[index] = resolveBatchEntry(ctx, client, songlink.Query{URL: urls[index], Country: country})
+			}
+		}()
+	}
+
+	for index := range urls {
+		jobs <- index
+	}
+	close(jobs)
+	wg.Wait()
+
+	return entries
+}
+
+// resolveBatchEntry resolves one URL within the -timeout duration
+func resolveBatchEntry(ctx context.Context, client *songlink.Client, query songlink.Query) batchEntry {
+	entry := batchEntry{inputURL: query.URL}
+	ctx, cancel := linkContext(ctx)
+	defer cancel()
+
+	// Don't spend API requests on lines that aren't music URLs
+	input, err := client.NormalizeURL(ctx, query.URL)
+	if err != nil {
+		entry.err = err
+		entry.unsupported = errors.Is(err, songlink.ErrUnsupportedURL)
+		return entry
+	}
+	query.URL = input.URL
+
+	entry.response, entry.err = fetchLinks(ctx, client, query)
+	var apiErr *songlink.APIError
+	if errors.As(entry.err, &apiErr) && apiErr.Unsupported() {
+		entry.unsupported = true
+	}
+	return entry
+}
+
+// writeBatch writes the entries in the requested format. In text format failed
+// entries are reported on stderr so that stdout only contains rendered links.
+// Entries rejected by -strict get their error set so that the summary counts them as failures.
+func writeBatch(w io.Writer, format OutputFormat, entries []batchEntry) error {
+	platforms, err := configuredPlatforms()
+	if err != nil {
+		return err
+	}
+	preference, err := configuredPreference()
+	if err != nil {
+		return err
+	}
+	keepLocale, err := keepLocale()
+	if err != nil {
+		return err
+	}
+
+	// With -strict, entries missing platform links are reported as failures
+	incomplete := 0
+	var recorded []HistoryEntry
+	if format != FormatText {
+		results := make([]LinkResult, len(entries))
+		for i := range entries {
+			entry := &entries[i]
+			if entry.err == nil && platforms != nil {
+				if err := checkLinks(entry.inputURL, selectLinks(entry.response, platforms, preference)); err != nil {
+					entry.err = err
+					incomplete++
+				}
+			}
+			if entry.err != nil {
+				results[i] = LinkResult{InputURL: entry.inputURL, Links: []PlatformLink{}, Error: entry.err.Error()}
+				continue
+			}
+			recorded = append(recorded, newHistoryEntry(entry.inputURL, entry.response, keepLocale, "", ""))
+			results[i] = NewLinkResult(entry.inputURL, entry.response, platforms, keepLocale)
+		}
+		recordHistory(recorded...)
+		if err := writeBatchResults(w, format, results, platforms); err != nil {
+			return err
+		}
+		return incompleteBatchError(incomplete, len(entries))
+	}
+
+	templateName, templateText, err := selectedTemplate()
+	if err != nil {
+		return err
+	}
+	check := linksShown(platforms, templateText)
+	for i := range entries {
+		entry := &entries[i]
+		if entry.err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", entry.inputURL, entry.err)
+			continue
+		}
+		selection := selectLinks(entry.response, platforms, preference)
+		if check {
+			if err := checkLinks(entry.inputURL, selection); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				entry.err = err
+				incomplete++
+				continue
+			}
+		}
+		data := NewTemplateData(NewLinkResult(entry.inputURL, entry.response, selection.Platforms, keepLocale), entry.response, preference)
+		output, err := renderTemplate(templateName, templateText, data)
+		if err != nil {
+			return err
+		}
+		recorded = append(recorded, newHistoryEntry(entry.inputURL, entry.response, keepLocale, templateName, output))
+		fmt.Fprintln(w, output)
+	}
+	recordHistory(recorded...)
+	return incompleteBatchError(incomplete, len(entries))
+}
+
+// incompleteBatchError returns an error if -strict rejected any of the entries for missing platform links
+func incompleteBatchError(incomplete, total int) error {
+	if incomplete == 0 {
+		return nil
+	}
+	return fmt.Errorf("%d of %d links are missing platform links", incomplete, total)
+}
+
+// printBatchSummary reports how many entries succeeded, failed or were unsupported
+func printBatchSummary(w io.Writer, entries []batchEntry) {
+	var succeeded, failed, unsupported int
+	for _, entry := range entries {
+		switch {
+		case entry.err == nil:
+			succeeded++
+		case entry.unsupported:
+			unsupported++
+		default:
+			failed++
+		}
+	}
+
+	fmt.Fprintf(w, "\nResolved %d URLs: %d succeeded, %d failed, %d unsupported\n", len(entries), succeeded, failed, unsupported)
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/marcusziade/songlink-cli.git/pkg/songlink"
+)
+
+func TestReadBatchURLs(t *testing.T) {
+	input := "https://open.spotify.com/track/1\n\n  # exported from chat\n  https://youtu.be/abc  \nhttps://tidal.com/track/2\n"
+	urls, err := readBatchURLs(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("readBatchURLs returned an unexpected error: %v", err)
+	}
+
+	expected := []string{"https://open.spotify.com/track/1", "https://youtu.be/abc", "https://tidal.com/track/2"}
+	if strings.Join(urls, " ") != strings.Join(expected, " ") {
+		t.Errorf("readBatchURLs() = %v; want %v", urls, expected)
+	}
+}
+
+func TestResolveBatchKeepsOrder(t *testing.T) {
+	urls := []string{"first", "ftp://second", "third", "mailto:fourth"}
+	entries := resolveBatch(context.Background(), songlink.NewClient(), "", urls, 3)
+
+	for i, entry := range entries {
+		if entry.inputURL != urls[i] {
+			t.Errorf("entry %d is %q; want %q", i, entry.inputURL, urls[i])
+		}
+		if !entry.unsupported {
+			t.Errorf("entry %q should be reported as unsupported", entry.inputURL)
+		}
+	}
+}
+
+func TestWriteBatchStrictCountsFailures(t *testing.T) {
+	platforms, strict := *platformsFlag, *strictFlag
+	*platformsFlag, *strictFlag = "tidal", true
+	defer func() { *platformsFlag, *strictFlag = platforms, strict }()
+
+	for _, format := range []OutputFormat{FormatText, FormatJSON} {
+		entries := []batchEntry{
+			{inputURL: "https://open.spotify.com/track/1", response: &songlink.Response{
+				LinksByPlatform: songlink.LinksByPlatform{"tidal": {URL: "https://tidal.com/track/1"}},
+			}},
+			{inputURL: "https://open.spotify.com/track/2", response: &songlink.Response{
+				LinksByPlatform: songlink.LinksByPlatform{"spotify": {URL: "https://open.spotify.com/track/2"}},
+			}},
+		}
+		if err := writeBatch(io.Discard, format, entries); err == nil {
+			t.Errorf("%s: writeBatch should return an error for the entry missing a tidal link", format)
+		}
+
+		var summary bytes.Buffer
+		printBatchSummary(&summary, entries)
+		if expected := "1 succeeded, 1 failed"; !strings.Contains(summary.String(), expected) {
+			t.Errorf("%s: summary = %q; want it to contain %q", format, summary.String(), expected)
+		}
+	}
+}
@@ -0,0 +1,326 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/marcusziade/songlink-cli.git/pkg/songlink"
+)
+
+// defaultCacheTTL is how long resolved responses are reused unless cache_ttl is set in the config
+const defaultCacheTTL = 7 * 24 * time.Hour
+
+// Cache stores decoded song.link responses on disk, one file per API endpoint, input URL and country
+type Cache struct {
+	dir string
+	ttl time.Duration
+}
+
+// cacheEntry is the on-disk format of a cached response
+type cacheEntry struct {
+	APIBaseURL string             `json:"api_base_url,omitempty"`
+	InputURL   string             `json:"input_url"`
+	Country    string             `json:"country,omitempty"`
+	FetchedAt  time.Time          `json:"fetched_at"`
+	Response   *songlink.Response `json:"response"`
+}
+
+// CacheStats summarizes the contents of the cache
+type CacheStats struct {
+	Entries int
+	Expired int
+	Bytes   int64
+	Oldest  time.Time
+	Newest  time.Time
+}
+
+// NewCache creates a cache storing entries in dir that expire after ttl
+func NewCache(dir string, ttl time.Duration) *Cache {
+	return &Cache{dir: dir, ttl: ttl}
+}
+
+var (
+	linkCache     *Cache
+	linkCacheErr  error
+	linkCacheOnce sync.Once
+)
+
+// getLinkCache returns the cache under ~/.songlink-cli/cache, or nil if cache_ttl disables it
+func getLinkCache() (*Cache, error) {
+	linkCacheOnce.Do(func() {
+		config, err := LoadConfig()
+		if err != nil {
+			linkCacheErr = fmt.Errorf("error loading config: %w", err)
+			return
+		}
+
+		ttl := defaultCacheTTL
+		if config.CacheTTL != "" {
+			ttl, err = time.ParseDuration(config.CacheTTL)
+			if err != nil {
+				linkCacheErr = fmt.Errorf("invalid cache_ttl %q: %w", config.CacheTTL, err)
+				return
+			}
+		}
+		if ttl <= 0 {
+			return
+		}
+
+		dir, err := linkCacheDir()
+		if err != nil {
+			linkCacheErr = err
+			return
+		}
+		linkCache = NewCache(dir, ttl)
+	})
+	return linkCache, linkCacheErr
+}
+
+// linkCacheDir returns ~/.songlink-cli/cache
+func linkCacheDir() (string, error) {
+	configDir, err := GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "cache"), nil
+}
+
+// Get returns the cached response from the API at apiBaseURL for inputURL and country if there is one that
+// hasn't expired
+func (c *Cache) Get(apiBaseURL, inputURL, country string) (*songlink.Response, bool) {
+	entry, err := c.read(c.path(apiBaseURL, inputURL, country))
+	if err != nil || c.expired(entry) {
+		return nil, false
+	}
+	return entry.Response, true
+}
+
+// Put stores the response from the API at apiBaseURL for inputURL and country
+func (c *Cache) Put(apiBaseURL, inputURL, country string, response *songlink.Response) error {
+	if err := os.MkdirAll(c.dir, 0700); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	data, err := json.Marshal(cacheEntry{
+		APIBaseURL: cacheAPIBaseURL(apiBaseURL),
+		InputURL:   inputURL,
+		Country:    country,
+		FetchedAt:  time.Now(),
+		Response:   response,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal cache entry: %w", err)
+	}
+
+	// Write to a temporary file first so that concurrent readers never see a partial entry
+	path := c.path(apiBaseURL, inputURL, country)
+	tmp, err := os.CreateTemp(c.dir, ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	tmp.Close()
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	return nil
+}
+
+// Stats returns the number, size and age of the cached entries
+func (c *Cache) Stats() (CacheStats, error) {
+	var stats CacheStats
+	err := c.walk(func(path string, info os.FileInfo, entry *cacheEntry) {
+		stats.Entries++
+		stats.Bytes += info.Size()
+		if entry == nil || c.expired(entry) {
+			stats.Expired++
+		}
+		if entry == nil {
+			return
+		}
+		if stats.Oldest.IsZero() || entry.FetchedAt.Before(stats.Oldest) {
+			stats.Oldest = entry.FetchedAt
+		}
+		if entry.FetchedAt.After(stats.Newest) {
+			stats.Newest = entry.FetchedAt
+		}
+	})
+	return stats, err
+}
+
+// Clear removes every cached entry and returns how many were removed
+func (c *Cache) Clear() (int, error) {
+	removed := 0
+	err := c.walk(func(path string, info os.FileInfo, entry *cacheEntry) {
+		if os.Remove(path) == nil {
+			removed++
+		}
+	})
+	return removed, err
+}
+
+// Prune removes expired and unreadable entries and returns how many were removed
+func (c *Cache) Prune() (int, error) {
+	removed := 0
+	err := c.walk(func(path string, info os.FileInfo, entry *cacheEntry) {
+		if entry == nil || c.expired(entry) {
+			if os.Remove(path) == nil {
+				removed++
+			}
+		}
+	})
+	return removed, err
+}
+
+// walk calls fn for every entry file. entry is nil if the file can't be decoded.
+func (c *Cache) walk(fn func(path string, info os.FileInfo, entry *cacheEntry)) error {
+	files, err := os.ReadDir(c.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read cache directory: %w", err)
+	}
+
+	for _, file := range files {
+		if file.IsDir() || filepath.Ext(file.Name()) != ".json" {
+			continue
+		}
+		info, err := file.Info()
+		if err != nil {
+			continue
+		}
+		path := filepath.Join(c.dir, file.Name())
+		entry, err := c.read(path)
+		if err != nil {
+			entry = nil
+		}
+		fn(path, info, entry)
+	}
+	return nil
+}
+
+func (c *Cache) read(path string) (*cacheEntry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, err
+	}
+	if entry.Response == nil {
+		return nil, errors.New("cache entry has no response")
+	}
+	return &entry, nil
+}
+
+func (c *Cache) expired(entry *cacheEntry) bool {
+	return time.Since(entry.FetchedAt) > c.ttl
+}
+
+// path returns the entry file for apiBaseURL, inputURL and country
+func (c *Cache) path(apiBaseURL, inputURL, country string) string {
+	return filepath.Join(c.dir, cacheKey(apiBaseURL, inputURL, country)+".json")
+}
+
+// cacheKey hashes the API endpoint, the normalized input URL and country, so that responses from a proxy or
+// mock server are never served for the public API or the other way around
+func cacheKey(apiBaseURL, inputURL, country string) string {
+	key := normalizeCacheURL(inputURL) + "\n" + strings.ToUpper(country) + "\n" + cacheAPIBaseURL(apiBaseURL)
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// cacheAPIBaseURL normalizes an API endpoint for the cache key
+func cacheAPIBaseURL(apiBaseURL string) string {
+	return strings.TrimSuffix(normalizeCacheURL(apiBaseURL), "/")
+}
+
+// normalizeCacheURL makes equivalent spellings of a URL share a cache entry: the scheme and host
+// are lowercased, the fragment and a trailing slash are dropped and query parameters are sorted
+func normalizeCacheURL(rawURL string) string {
+	rawURL = strings.TrimSpace(rawURL)
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Host == "" {
+		return rawURL
+	}
+
+	parsed.Scheme = strings.ToLower(parsed.Scheme)
+	parsed.Host = strings.ToLower(parsed.Host)
+	parsed.Fragment = ""
+	parsed.RawFragment = ""
+	parsed.Path = strings.TrimSuffix(parsed.Path, "/")
+	parsed.RawPath = ""
+	parsed.RawQuery = parsed.Query().Encode()
+	return parsed.String()
+}
+
+// executeCache handles the cache subcommand
+func executeCache(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: songlink-cli cache stats|clear|prune")
+	}
+
+	cache, err := getLinkCache()
+	if err != nil {
+		return err
+	}
+	if cache == nil {
+		if args[0] != "clear" {
+			return errors.New("the cache is disabled (cache_ttl is 0 in the config)")
+		}
+		// Entries written before the cache was disabled can still be removed
+		dir, err := linkCacheDir()
+		if err != nil {
+			return err
+		}
+		cache = NewCache(dir, 0)
+	}
+
+	switch args[0] {
+	case "stats":
+		stats, err := cache.Stats()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Location: %s\n", cache.dir)
+		fmt.Printf("TTL:      %s\n", cache.ttl)
+		fmt.Printf("Entries:  %d (%d expired)\n", stats.Entries, stats.Expired)
+		fmt.Printf("Size:     %.1f KB\n", float64(stats.Bytes)/1024)
+		if !stats.Oldest.IsZero() {
+			fmt.Printf("Oldest:   %s\n", stats.Oldest.Format(time.DateTime))
+			fmt.Printf("Newest:   %s\n", stats.Newest.Format(time.DateTime))
+		}
+	case "clear":
+		removed, err := cache.Clear()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Removed %d cached responses\n", removed)
+	case "prune":
+		removed, err := cache.Prune()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Removed %d expired cached responses\n", removed)
+	default:
+		return fmt.Errorf("unknown cache command %q (use stats, clear or prune)", args[0])
+	}
+	return nil
+}
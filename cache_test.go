@@ -0,0 +1,79 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/marcusziade/songlink-cli.git/pkg/songlink"
+)
+
+func TestCachePutGet(t *testing.T) {
+	cache := NewCache(t.TempDir(), time.Hour)
+	response := &songlink.Response{
+		PageURL:         "https://song.link/i/1572919354",
+		LinksByPlatform: songlink.LinksByPlatform{"spotify": {URL: "https://open.spotify.com/track/2Xtsv7BUMrNodQWH2JPOc0"}},
+	}
+
+	if err := cache.Put(songlink.DefaultBaseURL, "https://music.apple.com/fi/album/caravan/1572919347?i=1572919354", "", response); err != nil {
+		t.Fatalf("Put returned an unexpected error: %v", err)
+	}
+
+	// Equivalent spellings of the URL share the entry, other countries don't
+	cached, ok := cache.Get(songlink.DefaultBaseURL, "HTTPS://Music.Apple.com/fi/album/caravan/1572919347/?i=1572919354#top", "")
+	if !ok {
+		t.Fatal("Get missed an entry stored under an equivalent URL")
+	}
+	if cached.PageURL != response.PageURL || cached.LinksByPlatform["spotify"].URL != response.LinksByPlatform["spotify"].URL {
+		t.Errorf("Get returned %+v; want %+v", cached, response)
+	}
+	if _, ok := cache.Get(songlink.DefaultBaseURL, "https://music.apple.com/fi/album/caravan/1572919347?i=1572919354", "US"); ok {
+		t.Error("Get returned an entry stored for a different country")
+	}
+
+	// An endpoint's entries are shared however its URL is spelled, but not with other endpoints
+	if _, ok := cache.Get(songlink.DefaultBaseURL+"/", "https://music.apple.com/fi/album/caravan/1572919347?i=1572919354", ""); !ok {
+		t.Error("Get missed an entry stored under an equivalent API endpoint")
+	}
+	if _, ok := cache.Get("http://localhost:8080", "https://music.apple.com/fi/album/caravan/1572919347?i=1572919354", ""); ok {
+		t.Error("Get returned an entry stored for a different API endpoint")
+	}
+}
+
+func TestCachePrune(t *testing.T) {
+	dir := t.TempDir()
+	cache := NewCache(dir, time.Hour)
+	response := &songlink.Response{PageURL: "https://song.link/i/1"}
+
+	if err := cache.Put("", "https://example.com/fresh", "", response); err != nil {
+		t.Fatalf("Put returned an unexpected error: %v", err)
+	}
+	if err := cache.Put("", "https://example.com/stale", "", response); err != nil {
+		t.Fatalf("Put returned an unexpected error: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "broken.json"), []byte("{"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// A cache with a negative TTL treats every entry as expired
+	if _, ok := NewCache(dir, -time.Second).Get("", "https://example.com/stale", ""); ok {
+		t.Error("Get returned an expired entry")
+	}
+
+	removed, err := cache.Prune()
+	if err != nil {
+		t.Fatalf("Prune returned an unexpected error: %v", err)
+	}
+	if removed != 1 {
+		t.Errorf("Prune removed %d entries; want only the unreadable one", removed)
+	}
+
+	stats, err := cache.Stats()
+	if err != nil {
+		t.Fatalf("Stats returned an unexpected error: %v", err)
+	}
+	if stats.Entries != 2 || stats.Expired != 0 {
+		t.Errorf("Stats() = %+v; want 2 fresh entries", stats)
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	"image/png"
+	"net/http"
+	"os"
+	"strings"
+
+	_ "golang.org/x/image/webp"
+
+	"github.com/marcusziade/songlink-cli.git/pkg/card"
+	"github.com/marcusziade/songlink-cli.git/pkg/download"
+	"github.com/marcusziade/songlink-cli.git/pkg/search"
+	"github.com/marcusziade/songlink-cli.git/pkg/songlink"
+)
+
+// executeCard handles the card subcommand
+func executeCard(ctx context.Context, args []string) error {
+	cardCmd := flag.NewFlagSet("card", flag.ExitOnError)
+	presetFlag := cardCmd.String("preset", "square", "Card size: "+strings.Join(card.PresetNames(), ", "))
+	outFlag := cardCmd.String("out", "", "PNG file to write (default: \"Title — Artist.png\")")
+	searchFlag := cardCmd.String("search", "", "Search Apple Music and make a card for the selected result instead of a URL")
+	typeFlag := cardCmd.String("type", "song", "Type of search: song, album, or both")
+
+	if err := cardCmd.Parse(args); err != nil {
+		return err
+	}
+	preset, err := card.LookupPreset(*presetFlag)
+	if err != nil {
+		return err
+	}
+
+	var input, artworkURL string
+	if *searchFlag != "" {
+		if cardCmd.NArg() > 0 {
+			return errors.New("give either a URL or -search, not both")
+		}
+		selected, err := selectSearchResult(ctx, *searchFlag, search.Type(*typeFlag))
+		if err != nil {
+			return err
+		}
+		// The same artwork DownloadTrack uses for MP4 videos
+		input, artworkURL = selected.URL, selected.ArtworkURL
+	} else {
+		if input, err = readInputURL(cardCmd.Args()); err != nil {
+			return err
+		}
+	}
+
+	client, err := newSonglinkClient()
+	if err != nil {
+		return err
+	}
+	country, err := configuredCountry()
+	if err != nil {
+		return err
+	}
+	keepLocale, err := keepLocale()
+	if err != nil {
+		return err
+	}
+
+	linkCtx, cancel := linkContext(ctx)
+	defer cancel()
+	normalized, err := client.NormalizeURL(linkCtx, input)
+	if err != nil {
+		return err
+	}
+	response, err := fetchLinks(linkCtx, client, songlink.Query{URL: normalized.URL, Country: country})
+	if err != nil {
+		return err
+	}
+	result := NewLinkResult(input, response, nil, keepLocale)
+	if artworkURL == "" {
+		artworkURL = result.ThumbnailURL
+	}
+
+	content := card.Card{Title: result.Title, Artist: result.Artist, URL: result.PageURL}
+	for _, link := range result.Links {
+		content.Platforms = append(content.Platforms, songlink.PlatformName(link.Platform))
+	}
+	if artworkURL != "" {
+		// -timeout covers the artwork too, so that a stalled image host can't hang the command
+		artworkCtx, cancel := linkContext(ctx)
+		defer cancel()
+		if content.Artwork, err = fetchArtwork(artworkCtx, artworkURL); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v; making the card without artwork\n", err)
+		}
+	}
+
+	img, err := card.Render(content, preset)
+	if err != nil {
+		return err
+	}
+
+	path := *outFlag
+	if path == "" {
+		path = "card.png"
+		if result.Title != "" {
+			path = download.SanitizeFileName(result.Label()) + ".png"
+		}
+	}
+	if err := writePNG(path, img); err != nil {
+		return err
+	}
+	fmt.Printf("Saved the %s card for %s to %s\n", preset.Name, result.Label(), path)
+	return nil
+}
+
+// selectSearchResult searches the Apple Music catalog for query and lets the user pick a result
+func selectSearchResult(ctx context.Context, query string, searchType search.Type) (*search.Result, error) {
+	config, err := LoadConfig()
+	if err != nil {
+		return nil, fmt.Errorf("error loading config: %w", err)
+	}
+	if !config.HasAppleMusicCredentials() {
+		return nil, errors.New("searching uses the Apple Music catalog; run 'songlink-cli config' to set up credentials")
+	}
+	searcher, err := newSearchClient(config)
+	if err != nil {
+		return nil, fmt.Errorf("error creating music searcher: %w", err)
+	}
+
+	selected, err := DisplaySearchResults(ctx, os.Stdout, newResultPager(searcher, query, searchType, 0, 0))
+	if err != nil {
+		return nil, fmt.Errorf("error selecting result: %w", err)
+	}
+	return selected, nil
+}
+
+// fetchArtwork downloads and decodes a JPEG, PNG or WebP image
+func fetchArtwork(ctx context.Context, artworkURL string) (image.Image, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, artworkURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid artwork URL: %w", err)
+	}
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download artwork: %w", err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download artwork: %s", response.Status)
+	}
+
+	img, _, err := image.Decode(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode artwork: %w", err)
+	}
+	return img, nil
+}
+
+// writePNG encodes img to a PNG file at path
+func writePNG(path string, img image.Image) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("error creating %s: %w", path, err)
+	}
+	if err := png.Encode(file, img); err != nil {
+		file.Close()
+		return fmt.Errorf("error writing %s: %w", path, err)
+	}
+	return file.Close()
+}
@@ -7,17 +7,48 @@ import (
 	"path/filepath"
 )
 
-// Config holds the Apple Music API credentials
+// Config holds the Apple Music API credentials and CLI defaults
 type Config struct {
-	TeamID       string `json:"team_id"`
-	KeyID        string `json:"key_id"`
-	PrivateKey   string `json:"private_key"`
-	MusicID      string `json:"music_id"`
-	ConfigExists bool   `json:"-"`
+	TeamID     string `json:"team_id"`
+	KeyID      string `json:"key_id"`
+	PrivateKey string `json:"private_key"`
+	MusicID    string `json:"music_id"`
+	// Platforms selects which platform links are included in the output (e.g. ["spotify", "tidal"])
+	Platforms []string `json:"platforms,omitempty"`
+	// Templates are named output templates selectable with -t, in addition to the built-in ones
+	Templates map[string]string `json:"templates,omitempty"`
+	// DefaultTemplate is the template used when no output flag is given
+	DefaultTemplate string `json:"default_template,omitempty"`
+	// RateLimit is the number of song.link requests allowed per minute.
+	// 0 uses the unauthenticated quota of 10, a negative value disables the limiter.
+	RateLimit int `json:"rate_limit,omitempty"`
+	// APIKey is the song.link API key sent with every request
+	APIKey string `json:"api_key,omitempty"`
+	// APIBaseURL overrides the song.link API endpoint, e.g. to use a proxy or a mock server
+	APIBaseURL string `json:"api_base_url,omitempty"`
+	// CacheTTL is how long resolved links are cached, as a Go duration (e.g. "72h"). "0" disables the cache.
+	CacheTTL string `json:"cache_ttl,omitempty"`
+	// Country is the ISO 3166-1 alpha-2 code of the storefront links are resolved for (e.g. "FI")
+	Country string `json:"country,omitempty"`
+	// KeepLocale keeps the country segment in song.link page URLs (https://song.link/fi/i/...)
+	KeepLocale bool `json:"keep_locale,omitempty"`
+	// WatchIgnore lists domains whose links the clipboard watcher leaves alone
+	WatchIgnore []string `json:"watch_ignore,omitempty"`
+	// HistoryLimit is how many resolutions the history keeps. 0 keeps 1000, a negative value disables the history.
+	HistoryLimit int `json:"history_limit,omitempty"`
+	// PlatformPreference is the order platforms are tried in when no platforms are selected
+	// (e.g. ["spotify", "appleMusic", "youtubeMusic", "youtube"])
+	PlatformPreference []string `json:"platform_preference,omitempty"`
+	ConfigExists       bool     `json:"-"`
 }
 
-// GetConfigPath returns the path to the config file
-func GetConfigPath() (string, error) {
+// HasAppleMusicCredentials reports whether the Apple Music API credentials are set
+func (c *Config) HasAppleMusicCredentials() bool {
+	return c.ConfigExists && c.TeamID != "" && c.KeyID != "" && c.PrivateKey != ""
+}
+
+// GetConfigDir returns the directory holding the config file and other CLI state, creating it if needed
+func GetConfigDir() (string, error) {
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
 		return "", fmt.Errorf("failed to get home directory: %w", err)
@@ -29,6 +60,16 @@ func GetConfigPath() (string, error) {
 		return "", fmt.Errorf("failed to create config directory: %w", err)
 	}
 
+	return configDir, nil
+}
+
+// GetConfigPath returns the path to the config file
+func GetConfigPath() (string, error) {
+	configDir, err := GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+
 	return filepath.Join(configDir, "config.json"), nil
 }
 
@@ -78,4 +119,4 @@ func (c *Config) SaveConfig() error {
 	}
 
 	return nil
-}
\ No newline at end of file
+}
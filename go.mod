@@ -7,9 +7,13 @@ toolchain go1.24.1
 require (
 	github.com/atotto/clipboard v0.1.4
 	github.com/marcusziade/musickitkat v0.0.2
+	github.com/skip2/go-qrcode v0.0.0-20200617195104-da1b6568686e
+	golang.org/x/image v0.25.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
 	github.com/golang-jwt/jwt/v5 v5.2.1 // indirect
 	golang.org/x/oauth2 v0.28.0 // indirect
+	golang.org/x/text v0.23.0 // indirect
 )
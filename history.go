@@ -0,0 +1,512 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/atotto/clipboard"
+	"github.com/marcusziade/songlink-cli.git/pkg/songlink"
+)
+
+// defaultHistoryLimit is how many resolutions are kept unless history_limit is set in the config
+const defaultHistoryLimit = 1000
+
+// HistoryEntry is one successful resolution
+type HistoryEntry struct {
+	Time time.Time `json:"time"`
+	LinkResult
+	// Template is the name of the output template used, "custom" for -template, or empty for structured output
+	Template string `json:"template,omitempty"`
+	// Output is the text that was printed or copied
+	Output string `json:"output,omitempty"`
+}
+
+// History is an append-only log of resolutions stored as JSON lines.
+// Only the newest limit entries are kept.
+type History struct {
+	path  string
+	limit int
+	mu    sync.Mutex
+	// lines and size describe the file after this process last wrote it, so that appending doesn't have to count
+	// its lines again unless another process has changed it since
+	lines int
+	size  int64
+}
+
+// NewHistory creates a history stored at path keeping up to limit entries
+func NewHistory(path string, limit int) *History {
+	return &History{path: path, limit: limit}
+}
+
+var (
+	linkHistory     *History
+	linkHistoryErr  error
+	linkHistoryOnce sync.Once
+)
+
+// getHistory returns the history in ~/.songlink-cli/history.jsonl, or nil if a negative history_limit disables it
+func getHistory() (*History, error) {
+	linkHistoryOnce.Do(func() {
+		config, err := LoadConfig()
+		if err != nil {
+			linkHistoryErr = fmt.Errorf("error loading config: %w", err)
+			return
+		}
+		limit := config.HistoryLimit
+		if limit < 0 {
+			return
+		}
+		if limit == 0 {
+			limit = defaultHistoryLimit
+		}
+
+		configDir, err := GetConfigDir()
+		if err != nil {
+			linkHistoryErr = err
+			return
+		}
+		linkHistory = NewHistory(filepath.Join(configDir, "history.jsonl"), limit)
+	})
+	return linkHistory, linkHistoryErr
+}
+
+// newHistoryEntry records a resolution. The full response is recorded, not just the selected platforms.
+func newHistoryEntry(inputURL string, response *songlink.Response, keepLocale bool, template, output string) HistoryEntry {
+	return HistoryEntry{
+		Time:       time.Now(),
+		LinkResult: NewLinkResult(inputURL, response, nil, keepLocale),
+		Template:   template,
+		Output:     output,
+	}
+}
+
+// recordHistory adds resolutions to the history in one write.
+// Failing to record doesn't fail the resolution, so errors are only reported on stderr.
+func recordHistory(entries ...HistoryEntry) {
+	history, err := getHistory()
+	if err == nil && history != nil {
+		err = history.AppendAll(entries)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error recording history: %v\n", err)
+	}
+}
+
+// Append adds entry to the end of the history, dropping the oldest entries beyond the limit
+func (h *History) Append(entry HistoryEntry) error {
+	return h.AppendAll([]HistoryEntry{entry})
+}
+
+// AppendAll adds entries to the end of the history, dropping the oldest entries beyond the limit.
+// The file is only trimmed once it holds twice the limit so that most appends don't have to read it.
+func (h *History) AppendAll(entries []HistoryEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	var data []byte
+	for _, entry := range entries {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return fmt.Errorf("failed to marshal history entry: %w", err)
+		}
+		data = append(append(data, line...), '\n')
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	unlock, err := lockFile(h.path + ".lock")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	var size int64
+	if info, err := os.Stat(h.path); err == nil {
+		size = info.Size()
+	}
+	if size != h.size {
+		if h.lines, err = countLines(h.path); err != nil {
+			return err
+		}
+	}
+
+	file, err := os.OpenFile(h.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open history: %w", err)
+	}
+	_, err = file.Write(data)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		// The file may hold part of the data, so count again next time
+		h.size = -1
+		return fmt.Errorf("failed to write history: %w", err)
+	}
+	h.lines += len(entries)
+	h.size = size + int64(len(data))
+
+	if h.lines > 2*h.limit {
+		kept, err := h.read()
+		if err != nil {
+			return err
+		}
+		return h.write(kept)
+	}
+	return nil
+}
+
+// countLines returns the number of lines in the file at path without parsing them
+func countLines(path string) (int, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to open history: %w", err)
+	}
+	defer file.Close()
+
+	lines := 0
+	buf := make([]byte, 64*1024)
+	for {
+		n, err := file.Read(buf)
+		lines += bytes.Count(buf[:n], []byte{'\n'})
+		if err == io.EOF {
+			return lines, nil
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to read history: %w", err)
+		}
+	}
+}
+
+// Entries returns the recorded entries, oldest first
+func (h *History) Entries() ([]HistoryEntry, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.read()
+}
+
+// Clear removes every entry and returns how many there were
+func (h *History) Clear() (int, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	unlock, err := lockFile(h.path + ".lock")
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	entries, err := h.read()
+	if err != nil {
+		return 0, err
+	}
+	if err := os.Remove(h.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return 0, fmt.Errorf("failed to remove history: %w", err)
+	}
+	h.lines, h.size = 0, 0
+	return len(entries), nil
+}
+
+// read parses the history file and returns the newest limit entries.
+// Lines that can't be parsed, e.g. after a crash mid-write, are skipped.
+func (h *History) read() ([]HistoryEntry, error) {
+	file, err := os.Open(h.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open history: %w", err)
+	}
+	defer file.Close()
+
+	var entries []HistoryEntry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var entry HistoryEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err == nil {
+			entries = append(entries, entry)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+	if len(entries) > h.limit {
+		entries = entries[len(entries)-h.limit:]
+	}
+	return entries, nil
+}
+
+// write replaces the history file with entries atomically
+func (h *History) write(entries []HistoryEntry) error {
+	var data []byte
+	for _, entry := range entries {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return fmt.Errorf("failed to marshal history entry: %w", err)
+		}
+		data = append(append(data, line...), '\n')
+	}
+
+	tmpPath := h.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write history: %w", err)
+	}
+	if err := os.Rename(tmpPath, h.path); err != nil {
+		return fmt.Errorf("failed to write history: %w", err)
+	}
+	h.lines, h.size = len(entries), int64(len(data))
+	return nil
+}
+
+// HistoryFilter selects history entries
+type HistoryFilter struct {
+	// Query matches the title, artist, input URL, page URL or any platform link, case-insensitively
+	Query string
+	// Since and Until bound the time of the entries; zero values leave that side open
+	Since time.Time
+	Until time.Time
+}
+
+// Match reports whether entry is selected by the filter
+func (f HistoryFilter) Match(entry HistoryEntry) bool {
+	if !f.Since.IsZero() && entry.Time.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && !entry.Time.Before(f.Until) {
+		return false
+	}
+	if f.Query == "" {
+		return true
+	}
+
+	query := strings.ToLower(f.Query)
+	fields := []string{entry.Title, entry.Artist, entry.InputURL, entry.PageURL}
+	for _, link := range entry.Links {
+		fields = append(fields, link.URL)
+	}
+	for _, field := range fields {
+		if strings.Contains(strings.ToLower(field), query) {
+			return true
+		}
+	}
+	return false
+}
+
+// numberedEntry is a history entry with its number, counting from 1 for the most recent entry
+type numberedEntry struct {
+	Number int
+	HistoryEntry
+}
+
+// filterHistory returns the entries matching filter, newest first
+func filterHistory(entries []HistoryEntry, filter HistoryFilter) []numberedEntry {
+	var matched []numberedEntry
+	for i := len(entries) - 1; i >= 0; i-- {
+		if filter.Match(entries[i]) {
+			matched = append(matched, numberedEntry{Number: len(entries) - i, HistoryEntry: entries[i]})
+		}
+	}
+	return matched
+}
+
+// parseHistoryTime parses the value of -since or -until: a date (2006-01-02), a date and time
+// (2006-01-02 15:04) or a duration before now such as 36h or 7d
+func parseHistoryTime(value string, now time.Time) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n >= 0 {
+			return now.AddDate(0, 0, -n), nil
+		}
+	}
+	if duration, err := time.ParseDuration(value); err == nil && duration >= 0 {
+		return now.Add(-duration), nil
+	}
+	for _, layout := range []string{time.DateOnly, "2006-01-02 15:04", time.DateTime, time.RFC3339} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q (use a date such as 2024-05-01, or a duration such as 24h or 7d)", value)
+}
+
+// executeHistory handles the history subcommand
+func executeHistory(ctx context.Context, args []string) error {
+	command := "list"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command, args = args[0], args[1:]
+	}
+
+	history, err := getHistory()
+	if err != nil {
+		return err
+	}
+	if history == nil {
+		return errors.New("history is disabled (history_limit is negative in the config)")
+	}
+
+	switch command {
+	case "list", "export":
+		return listHistory(history, command, args)
+	case "copy":
+		return copyHistory(history, args)
+	case "clear":
+		removed, err := history.Clear()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Removed %d history entries\n", removed)
+		return nil
+	default:
+		return fmt.Errorf("unknown history command %q (use list, copy, export or clear)", command)
+	}
+}
+
+// listHistory prints matching entries as a table (list) or writes them as JSON or CSV (export)
+func listHistory(history *History, command string, args []string) error {
+	historyCmd := flag.NewFlagSet("history "+command, flag.ExitOnError)
+	queryFlag := historyCmd.String("q", "", "Only show entries whose title, artist or links contain this text")
+	sinceFlag := historyCmd.String("since", "", "Only show entries from this date (2024-05-01) or duration ago (24h, 7d)")
+	untilFlag := historyCmd.String("until", "", "Only show entries before this date or duration ago")
+	var limitFlag *int
+	var formatFlag *string
+	if command == "list" {
+		limitFlag = historyCmd.Int("n", 20, "Number of entries to list, 0 for all")
+	} else {
+		formatFlag = historyCmd.String("format", "json", "Export format: json or csv")
+	}
+
+	if err := historyCmd.Parse(args); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	filter := HistoryFilter{Query: *queryFlag}
+	var err error
+	if filter.Since, err = parseHistoryTime(*sinceFlag, now); err != nil {
+		return err
+	}
+	if filter.Until, err = parseHistoryTime(*untilFlag, now); err != nil {
+		return err
+	}
+
+	entries, err := history.Entries()
+	if err != nil {
+		return err
+	}
+	matched := filterHistory(entries, filter)
+
+	if command == "export" {
+		exported := []HistoryEntry{}
+		for _, entry := range matched {
+			exported = append(exported, entry.HistoryEntry)
+		}
+		switch strings.ToLower(*formatFlag) {
+		case "json":
+			return writeJSON(os.Stdout, exported)
+		case "csv":
+			return writeHistoryCSV(os.Stdout, exported)
+		default:
+			return fmt.Errorf("unknown export format %q (use json or csv)", *formatFlag)
+		}
+	}
+
+	if len(matched) == 0 {
+		fmt.Println("No history entries found")
+		return nil
+	}
+	if *limitFlag > 0 && len(matched) > *limitFlag {
+		matched = matched[:*limitFlag]
+	}
+	return writeHistoryTable(os.Stdout, matched)
+}
+
+// copyHistory copies the output of entry N of the list (1 is the most recent) to the clipboard
+func copyHistory(history *History, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: songlink-cli history copy <n>")
+	}
+	n, err := strconv.Atoi(args[0])
+	if err != nil || n < 1 {
+		return fmt.Errorf("invalid entry number %q", args[0])
+	}
+
+	entries, err := history.Entries()
+	if err != nil {
+		return err
+	}
+	if n > len(entries) {
+		return fmt.Errorf("there are only %d history entries", len(entries))
+	}
+	entry := entries[len(entries)-n]
+
+	output := entry.Output
+	if output == "" {
+		output = entry.PageURL
+	}
+	if err := clipboard.WriteAll(output); err != nil {
+		return fmt.Errorf("error copying output string to clipboard: %w", err)
+	}
+	fmt.Printf("%s\nCopied to the clipboard\n", output)
+	return nil
+}
+
+// writeHistoryTable prints entries with the numbers history copy takes
+func writeHistoryTable(w io.Writer, entries []numberedEntry) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "#\tTIME\tTITLE\tPAGE URL")
+	for _, entry := range entries {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\n", entry.Number, entry.Time.Local().Format("2006-01-02 15:04"), entry.Label(), entry.PageURL)
+	}
+	return tw.Flush()
+}
+
+// writeHistoryCSV writes one row per entry with a column for every platform that has a link in any entry
+func writeHistoryCSV(w io.Writer, entries []HistoryEntry) error {
+	var columns []string
+	for _, platform := range songlink.Platforms {
+		for _, entry := range entries {
+			if entry.Link(platform) != "" {
+				columns = append(columns, platform)
+				break
+			}
+		}
+	}
+
+	writer := csv.NewWriter(w)
+	header := append([]string{"time", "input_url", "page_url", "title", "artist", "type", "template", "output"}, columns...)
+	if err := writer.Write(header); err != nil {
+		return fmt.Errorf("error writing table header: %w", err)
+	}
+	for _, entry := range entries {
+		row := []string{entry.Time.Format(time.RFC3339), entry.InputURL, entry.PageURL, entry.Title, entry.Artist, entry.Type, entry.Template, entry.Output}
+		for _, platform := range columns {
+			row = append(row, entry.Link(platform))
+		}
+		if err := writer.Write(row); err != nil {
+			return fmt.Errorf("error writing table row: %w", err)
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
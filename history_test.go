@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHistoryAppendKeepsLimit(t *testing.T) {
+	history := NewHistory(filepath.Join(t.TempDir(), "history.jsonl"), 3)
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 5; i++ {
+		entry := HistoryEntry{
+			Time:       start.Add(time.Duration(i) * time.Hour),
+			LinkResult: LinkResult{PageURL: fmt.Sprintf("https://song.link/i/%d", i+1), Links: []PlatformLink{}},
+			Template:   "songlink",
+		}
+		if err := history.Append(entry); err != nil {
+			t.Fatalf("Append returned an unexpected error: %v", err)
+		}
+	}
+
+	entries, err := history.Entries()
+	if err != nil {
+		t.Fatalf("Entries returned an unexpected error: %v", err)
+	}
+	var pages []string
+	for _, entry := range entries {
+		pages = append(pages, entry.PageURL)
+	}
+	expected := "https://song.link/i/3 https://song.link/i/4 https://song.link/i/5"
+	if strings.Join(pages, " ") != expected {
+		t.Errorf("Entries() = %v; want %s", pages, expected)
+	}
+	if !entries[0].Time.Equal(start.Add(2*time.Hour)) || entries[0].Template != "songlink" {
+		t.Errorf("oldest kept entry = %+v", entries[0])
+	}
+}
+
+func TestHistoryAppendAllTrimsAtTwiceTheLimit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	history := NewHistory(path, 2)
+
+	var entries []HistoryEntry
+	for i := 0; i < 4; i++ {
+		entries = append(entries, HistoryEntry{LinkResult: LinkResult{PageURL: fmt.Sprintf("https://song.link/i/%d", i+1), Links: []PlatformLink{}}})
+	}
+	if err := history.AppendAll(entries); err != nil {
+		t.Fatalf("AppendAll returned an unexpected error: %v", err)
+	}
+	if lines, _ := countLines(path); lines != 4 {
+		t.Errorf("the file has %d lines after reaching twice the limit; want it untrimmed with 4", lines)
+	}
+	if kept, _ := history.Entries(); len(kept) != 2 || kept[0].PageURL != "https://song.link/i/3" {
+		t.Errorf("Entries() = %+v; want the newest 2", kept)
+	}
+
+	// Another process trimming the file is noticed from its size, so the stale count doesn't trim it again
+	other := NewHistory(path, 2)
+	if err := other.Append(entries[0]); err != nil {
+		t.Fatalf("Append returned an unexpected error: %v", err)
+	}
+	if lines, _ := countLines(path); lines != 2 {
+		t.Errorf("the file has %d lines after going over twice the limit; want it trimmed to 2", lines)
+	}
+	if err := history.Append(entries[1]); err != nil {
+		t.Fatalf("Append returned an unexpected error: %v", err)
+	}
+	if lines, _ := countLines(path); lines != 3 {
+		t.Errorf("the file has %d lines; want 3", lines)
+	}
+}
+
+func TestFilterHistory(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 5, d, 12, 0, 0, 0, time.Local) }
+	entries := []HistoryEntry{
+		{Time: day(1), LinkResult: LinkResult{Title: "Caravan", Artist: "Duke Ellington"}},
+		{Time: day(2), LinkResult: LinkResult{Title: "So What", Artist: "Miles Davis", Links: []PlatformLink{{Platform: "tidal", URL: "https://tidal.com/track/42"}}}},
+		{Time: day(3), LinkResult: LinkResult{Title: "Take Five", Artist: "Dave Brubeck"}},
+	}
+
+	for _, tt := range []struct {
+		filter   HistoryFilter
+		expected []int
+	}{
+		{HistoryFilter{}, []int{1, 2, 3}},
+		{HistoryFilter{Query: "DAV"}, []int{1, 2}},
+		{HistoryFilter{Query: "tidal.com/track/42"}, []int{2}},
+		{HistoryFilter{Since: day(2)}, []int{1, 2}},
+		{HistoryFilter{Until: day(2)}, []int{3}},
+	} {
+		var numbers []int
+		for _, entry := range filterHistory(entries, tt.filter) {
+			numbers = append(numbers, entry.Number)
+		}
+		if fmt.Sprint(numbers) != fmt.Sprint(tt.expected) {
+			t.Errorf("filterHistory(%+v) numbers = %v; want %v", tt.filter, numbers, tt.expected)
+		}
+	}
+}
+
+func TestParseHistoryTime(t *testing.T) {
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.Local)
+	for value, expected := range map[string]time.Time{
+		"":                 {},
+		"36h":              now.Add(-36 * time.Hour),
+		"7d":               now.AddDate(0, 0, -7),
+		"2024-05-01":       time.Date(2024, 5, 1, 0, 0, 0, 0, time.Local),
+		"2024-05-01 18:30": time.Date(2024, 5, 1, 18, 30, 0, 0, time.Local),
+	} {
+		actual, err := parseHistoryTime(value, now)
+		if err != nil {
+			t.Errorf("parseHistoryTime(%q) returned an unexpected error: %v", value, err)
+		} else if !actual.Equal(expected) {
+			t.Errorf("parseHistoryTime(%q) = %s; want %s", value, actual, expected)
+		}
+	}
+
+	if _, err := parseHistoryTime("last week", now); err == nil {
+		t.Error("parseHistoryTime should reject unknown formats")
+	}
+}
@@ -1,28 +1,55 @@
 package main
 
 import (
-   "context"
-   "flag"
-   "fmt"
-   "os"
-   "strings"
-   "sync"
-   "time"
-
-   "github.com/atotto/clipboard"
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/atotto/clipboard"
+	"github.com/marcusziade/songlink-cli.git/pkg/search"
+	"github.com/marcusziade/songlink-cli.git/pkg/songlink"
 )
 
 var (
 	xFlag = flag.Bool("x", false, "Return the song.link URL without surrounding <>")
-	dFlag = flag.Bool("d", false, "Return the song.link URL surrounded by <> and the Spotify URL")
-	sFlag = flag.Bool("s", false, "Return only the Spotify URL")
+	dFlag = flag.Bool("d", false, "Return the song.link URL surrounded by <> and the preferred platform URL")
+	sFlag = flag.Bool("s", false, "Return only the preferred platform URL")
+
+	templateNameFlag = flag.String("t", "", "Named output template: songlink, twitter, discord, links, slack, mastodon, markdown, html, bbcode or one from the config")
+	templateFlag     = flag.String("template", "", "Custom output template (Go text/template), e.g. '{{.Label}} {{.PageURL}}'")
+	outputFlag       = flag.String("o", "text", "Output format: text, json, yaml, csv or tsv")
+	noCopyFlag       = flag.Bool("no-copy", false, "Print the output to stdout without copying it to the clipboard")
+	countryFlag      = flag.String("country", "", "Two-letter country code to resolve links for (e.g. US, GB, FI)")
+	keepLocaleFlag   = flag.Bool("keep-locale", false, "Keep the country segment in the song.link URL")
+	noCacheFlag      = flag.Bool("no-cache", false, "Don't read or write the cache of resolved links")
+	refreshFlag      = flag.Bool("refresh", false, "Resolve links again and update the cache")
+	apiKeyFlag       = flag.String("api-key", "", "song.link API key (default: $SONGLINK_API_KEY or api_key from the config)")
+	apiBaseURLFlag   = flag.String("api-base-url", "", "song.link API base URL (default: $SONGLINK_API_BASE_URL, api_base_url from the config or "+songlink.DefaultBaseURL+")")
+	platformsFlag    = flag.String("platforms", "", "Comma-separated platforms to include with -x, -d and -s (default: the first available platform from -prefer)")
+	preferFlag       = flag.String("prefer", "", "Comma-separated platform preference chain used when -platforms isn't set (default: spotify,appleMusic,youtubeMusic,youtube)")
+	albumFlag        = flag.Bool("album", false, "Resolve the album of a song link instead of the song")
+	albumAlsoFlag    = flag.Bool("album-also", false, "Resolve the album of a song link and output it after the song")
+	verifyFlag       = verifyModeVar("verify", "Check that each platform link is the same song as the input and ask, drop or keep the suspicious ones (-verify alone asks)")
+	qrFlag           = flag.Bool("qr", false, "Show the song.link URL as a QR code in the terminal")
+	qrOutFlag        = flag.String("qr-out", "", "Write the song.link URL as a QR code to a PNG file")
+	strictFlag       = flag.Bool("strict", false, "Exit with an error instead of a warning when selected platform links are missing")
+	timeoutFlag      = flag.Duration("timeout", 30*time.Second, "How long to wait for a link to resolve, including retries (0 waits indefinitely)")
 )
 
 // Command represents a CLI command
 type Command struct {
 	Name        string
 	Description string
-	Execute     func(args []string) error
+	Execute     func(ctx context.Context, args []string) error
 }
 
 // Commands available in the application
@@ -32,225 +59,411 @@ var commands = []Command{
 		Description: "Search for a song or album and get its links",
 		Execute:     executeSearch,
 	},
-   {
-       Name:        "config",
-       Description: "Configure Apple Music API credentials",
-       Execute:     executeConfig,
-   },
-   {
-       Name:        "download",
-       Description: "Search for a song or album and download it as mp3 or mp4",
-       Execute:     executeDownload,
-   },
+	{
+		Name:        "config",
+		Description: "Configure Apple Music API credentials",
+		Execute:     executeConfig,
+	},
+	{
+		Name:        "download",
+		Description: "Search for a song or album and download it as mp3 or mp4",
+		Execute:     executeDownload,
+	},
+	{
+		Name:        "resolve",
+		Description: "Resolve a song or album by platform ID, ISRC or UPC",
+		Execute:     executeResolve,
+	},
+	{
+		Name:        "rewrite",
+		Description: "Replace every music link in text from stdin or the clipboard",
+		Execute:     executeRewrite,
+	},
+	{
+		Name:        "watch",
+		Description: "Convert music links as they are copied to the clipboard",
+		Execute:     executeWatch,
+	},
+	{
+		Name:        "serve",
+		Description: "Serve link resolution, search and downloads over a local HTTP API",
+		Execute:     executeServe,
+	},
+	{
+		Name:        "availability",
+		Description: "Show which platforms have a link for a URL in each country",
+		Execute:     executeAvailability,
+	},
+	{
+		Name:        "card",
+		Description: "Make a share card image with the artwork, title and a QR code",
+		Execute:     executeCard,
+	},
+	{
+		Name:        "history",
+		Description: "List, search, copy or export previously resolved links",
+		Execute:     executeHistory,
+	},
+	{
+		Name:        "cache",
+		Description: "Show, clear or prune the cache of resolved links",
+		Execute:     executeCache,
+	},
+	{
+		Name:        "batch",
+		Description: "Resolve many URLs from a file or stdin, one per line",
+		Execute:     executeBatch,
+	},
 }
 
 func main() {
 	// Define base flags
 	flag.Parse()
 
+	// Ctrl-C cancels the running command so that it can stop cleanly. A second Ctrl-C exits immediately.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
 	// Check if a subcommand is provided
 	args := flag.Args()
 	if len(args) > 0 {
 		subcommand := args[0]
-		
+
 		// Find and execute the appropriate command
 		for _, cmd := range commands {
 			if cmd.Name == subcommand {
-				err := cmd.Execute(args[1:])
-				if err != nil {
-					fmt.Println("An error occurred:", err)
-					os.Exit(1)
-				}
+				exitOnError(cmd.Execute(ctx, args[1:]))
 				return
 			}
 		}
-		
+
 		// If we get here, the subcommand wasn't recognized
-		fmt.Printf("Unknown command: %s\n\n", subcommand)
-		printUsage()
-		os.Exit(1)
+		if !isInputArg(subcommand) {
+			fmt.Printf("Unknown command: %s\n\n", subcommand)
+			printUsage()
+			os.Exit(1)
+		}
 	}
 
 	// No subcommand provided, run the default behavior
-	err := runDefault()
-	if err != nil {
-		fmt.Println("An error occurred:", err)
-		os.Exit(1)
+	exitOnError(runDefault(ctx, args))
+}
+
+// exitOnError reports err and exits with a non-zero status if it isn't nil
+func exitOnError(err error) {
+	if err == nil {
+		return
 	}
+	if errors.Is(err, context.Canceled) {
+		fmt.Println("Interrupted")
+		os.Exit(130)
+	}
+	fmt.Println("An error occurred:", err)
+	os.Exit(1)
 }
 
 // executeSearch handles the search subcommand
-func executeSearch(args []string) error {
-   // Define search flags
-   searchCmd := flag.NewFlagSet("search", flag.ExitOnError)
-   typeFlag := searchCmd.String("type", "song", "Type of search: song, album, or both (default: song)")
-   outFlag := searchCmd.String("out", "downloads", "Output directory for downloaded files")
-   debugFlag := searchCmd.Bool("debug", false, "Enable debug logging during download")
-	
+func executeSearch(ctx context.Context, args []string) error {
+	// Define search flags
+	searchCmd := flag.NewFlagSet("search", flag.ExitOnError)
+	typeFlag := searchCmd.String("type", "song", "Type of search: song, album, or both (default: song)")
+	outFlag := searchCmd.String("out", "downloads", "Output directory for downloaded files")
+	debugFlag := searchCmd.Bool("debug", false, "Enable debug logging during download")
+	limitFlag := searchCmd.Int("limit", search.DefaultLimit, fmt.Sprintf("Number of results of each type per page, up to %d", search.MaxLimit))
+	offsetFlag := searchCmd.Int("offset", 0, "Number of results of each type to skip")
+	searchCmd.BoolVar(qrFlag, "qr", *qrFlag, "Show the song.link URL of the selected result as a QR code")
+	searchCmd.StringVar(qrOutFlag, "qr-out", *qrOutFlag, "Write the song.link URL of the selected result as a QR code to a PNG file")
+
 	// Parse search flags
 	if err := searchCmd.Parse(args); err != nil {
 		return err
 	}
-	
+
 	// Get search query
 	searchArgs := searchCmd.Args()
 	if len(searchArgs) == 0 {
 		return fmt.Errorf("search query required")
 	}
-	
+
 	query := searchArgs[0]
-	
+
 	// Determine search type
-	var searchType SearchType
+	var searchType search.Type
 	switch *typeFlag {
 	case "song":
-		searchType = Song
+		searchType = search.Song
 	case "album":
-		searchType = Album
+		searchType = search.Album
 	default:
 		// Use Both to search for songs and albums
-		searchType = Both
+		searchType = search.Both
 	}
-	
-   // Handle search
-   return HandleSearch(query, searchType, *outFlag, *debugFlag)
+
+	// Handle search
+	return HandleSearch(ctx, query, searchType, *limitFlag, *offsetFlag, *outFlag, *debugFlag)
 }
 
 // executeConfig handles the config subcommand
-func executeConfig(args []string) error {
+func executeConfig(ctx context.Context, args []string) error {
 	fmt.Println("Configuring Apple Music API credentials...")
-   return RunOnboarding()
+	return RunOnboarding(os.Stdout)
 }
 
 // executeDownload handles the download subcommand
-func executeDownload(args []string) error {
-   // Define download flags
-   downloadCmd := flag.NewFlagSet("download", flag.ExitOnError)
-   typeFlag := downloadCmd.String("type", "song", "Type of search: song, album, or both (default: song)")
-   formatFlag := downloadCmd.String("format", "mp3", "Download format: mp3 or mp4 (default: mp3)")
-   outFlag := downloadCmd.String("out", "downloads", "Output directory for downloaded files")
-   debugFlag := downloadCmd.Bool("debug", false, "Enable debug logging (show yt-dlp/ffmpeg output)")
-
-   // Parse flags
-   if err := downloadCmd.Parse(args); err != nil {
-       return err
-   }
-
-   // Get search query
-   queryArgs := downloadCmd.Args()
-   if len(queryArgs) == 0 {
-       return fmt.Errorf("download query required")
-   }
-   query := strings.Join(queryArgs, " ")
-
-   // Determine search type
-   var searchType SearchType
-   switch *typeFlag {
-   case "song":
-       searchType = Song
-   case "album":
-       searchType = Album
-   default:
-       searchType = Song
-   }
-
-   // Load config
-   config, err := LoadConfig()
-   if err != nil {
-       return fmt.Errorf("error loading config: %w", err)
-   }
-   if !config.ConfigExists {
-       fmt.Println("Apple Music API credentials not found. Let's set them up.")
-       if err := RunOnboarding(); err != nil {
-           return fmt.Errorf("error during onboarding: %w", err)
-       }
-       config, err = LoadConfig()
-       if err != nil {
-           return fmt.Errorf("error loading config after onboarding: %w", err)
-       }
-   }
-
-   // Create music searcher
-   searcher, err := NewMusicSearcher(config)
-   if err != nil {
-       return fmt.Errorf("error creating music searcher: %w", err)
-   }
-
-   // Search for music
-   ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-   defer cancel()
-   results, err := searcher.Search(ctx, query, searchType)
-   if err != nil {
-       return fmt.Errorf("error searching: %w", err)
-   }
-
-   // Display results and select
-   selected, err := DisplaySearchResults(results)
-   if err != nil {
-       return fmt.Errorf("error selecting result: %w", err)
-   }
-   fmt.Printf("\nSelected: %s - %s\n", selected.Name, selected.ArtistName)
-
-   // Download track via YouTube
-   fmt.Print("Downloading... ")
-   path, err := DownloadTrack(selected.Name, selected.ArtistName, selected.ArtworkURL, *formatFlag, *outFlag, *debugFlag)
-   if err != nil {
-       return fmt.Errorf("download error: %w", err)
-   }
-   fmt.Printf("Done. Saved to %s\n", path)
-   return nil
+func executeDownload(ctx context.Context, args []string) error {
+	// Define download flags
+	downloadCmd := flag.NewFlagSet("download", flag.ExitOnError)
+	typeFlag := downloadCmd.String("type", "song", "Type of search: song, album, or both (default: song)")
+	formatFlag := downloadCmd.String("format", "mp3", "Download format: mp3 or mp4 (default: mp3)")
+	outFlag := downloadCmd.String("out", "downloads", "Output directory for downloaded files")
+	debugFlag := downloadCmd.Bool("debug", false, "Enable debug logging (show yt-dlp/ffmpeg output)")
+	limitFlag := downloadCmd.Int("limit", search.DefaultLimit, fmt.Sprintf("Number of results per page, up to %d", search.MaxLimit))
+	offsetFlag := downloadCmd.Int("offset", 0, "Number of results to skip")
+
+	// Parse flags
+	if err := downloadCmd.Parse(args); err != nil {
+		return err
+	}
+
+	// Get search query
+	queryArgs := downloadCmd.Args()
+	if len(queryArgs) == 0 {
+		return fmt.Errorf("download query required")
+	}
+	query := strings.Join(queryArgs, " ")
+
+	// Determine search type
+	var searchType search.Type
+	switch *typeFlag {
+	case "song":
+		searchType = search.Song
+	case "album":
+		searchType = search.Album
+	default:
+		searchType = search.Song
+	}
+
+	// Load config
+	config, err := LoadConfig()
+	if err != nil {
+		return fmt.Errorf("error loading config: %w", err)
+	}
+	if !config.HasAppleMusicCredentials() {
+		fmt.Println("Apple Music API credentials not found. Let's set them up.")
+		if err := RunOnboarding(os.Stdout); err != nil {
+			return fmt.Errorf("error during onboarding: %w", err)
+		}
+		config, err = LoadConfig()
+		if err != nil {
+			return fmt.Errorf("error loading config after onboarding: %w", err)
+		}
+	}
+
+	// Create music searcher
+	searcher, err := newSearchClient(config)
+	if err != nil {
+		return fmt.Errorf("error creating music searcher: %w", err)
+	}
+
+	// Display results and select, fetching pages as they are shown
+	selected, err := DisplaySearchResults(ctx, os.Stdout, newResultPager(searcher, query, searchType, *limitFlag, *offsetFlag))
+	if err != nil {
+		return fmt.Errorf("error selecting result: %w", err)
+	}
+	fmt.Printf("\nSelected: %s - %s\n", selected.Name, selected.ArtistName)
+
+	// Download track via YouTube
+	fmt.Print("Downloading... ")
+	path, err := DownloadTrack(ctx, selected.Name, selected.ArtistName, selected.ArtworkURL, *formatFlag, *outFlag, *debugFlag)
+	if err != nil {
+		return fmt.Errorf("download error: %w", err)
+	}
+	fmt.Printf("Done. Saved to %s\n", path)
+	return nil
 }
 
-// runDefault runs the default behavior (process URL from clipboard)
-func runDefault() error {
-	searchURL, err := clipboard.ReadAll()
+// runDefault runs the default behavior (process a URL from the arguments, stdin or the clipboard)
+func runDefault(ctx context.Context, args []string) error {
+	format, err := ParseOutputFormat(*outputFlag)
 	if err != nil {
-		return fmt.Errorf("error reading clipboard: %w", err)
+		return err
 	}
 
+	searchURL, err := readInputURL(args)
+	if err != nil {
+		return err
+	}
+
+	// The loading indicator would end up in piped output, so only show it when copying text.
+	// It would also overwrite the questions of -verify=ask.
+	showProgress := format == FormatText && !*noCopyFlag && !strings.EqualFold(strings.TrimSpace(*verifyFlag), verifyAsk)
 	var wg sync.WaitGroup
-	wg.Add(1)
 	stopLoading := make(chan bool)
-	go func() {
-		defer wg.Done()
-		loadingIndicator(stopLoading)
-	}()
+	if showProgress {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			loadingIndicator(os.Stdout, stopLoading)
+		}()
+		// Stop the indicator however GetLinks returns so that errors and interrupts start on a clean line
+		defer func() {
+			stopLoading <- true
+			wg.Wait()
+		}()
+	}
 
-	err = GetLinks(searchURL)
+	err = GetLinks(ctx, searchURL)
 	if err != nil {
 		return fmt.Errorf("error getting links: %w", err)
 	}
 
-	stopLoading <- true
-	wg.Wait()
-
 	return nil
 }
 
+// isInputArg reports whether a positional argument is a URL (or "-" for stdin) rather than a subcommand
+func isInputArg(arg string) bool {
+	lower := strings.ToLower(arg)
+	return arg == "-" || strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://")
+}
+
+// readInputURL returns the URL to resolve: the first argument, the first line of stdin
+// when the argument is "-", or the clipboard contents when there are no arguments
+func readInputURL(args []string) (string, error) {
+	if len(args) > 1 {
+		return "", fmt.Errorf("expected a single URL, got %d arguments", len(args))
+	}
+
+	if len(args) == 1 && args[0] != "-" {
+		return strings.TrimSpace(args[0]), nil
+	}
+
+	if len(args) == 1 {
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			if line := strings.TrimSpace(scanner.Text()); line != "" {
+				return line, nil
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return "", fmt.Errorf("error reading stdin: %w", err)
+		}
+		return "", errors.New("no URL found on stdin")
+	}
+
+	searchURL, err := clipboard.ReadAll()
+	if err != nil {
+		return "", fmt.Errorf("error reading clipboard (pass a URL as an argument or - to read from stdin): %w", err)
+	}
+	searchURL = strings.TrimSpace(searchURL)
+	if searchURL == "" {
+		return "", errors.New("clipboard is empty (pass a URL as an argument or - to read from stdin)")
+	}
+	return searchURL, nil
+}
+
 // printUsage prints usage information
 func printUsage() {
 	fmt.Println("Usage:")
 	fmt.Println("  songlink-cli [flags]                 Process URL from clipboard")
+	fmt.Println("  songlink-cli [flags] <url>           Process the given URL")
+	fmt.Println("  songlink-cli [flags] -               Process the URL read from stdin")
 	fmt.Println("  songlink-cli search [flags] <query>  Search for a song or album")
 	fmt.Println("  songlink-cli config                  Configure Apple Music API credentials")
+	fmt.Println("  songlink-cli batch [flags] [file]    Resolve one URL per line from a file or stdin")
+	fmt.Println("  songlink-cli cache stats|clear|prune Manage the cache of resolved links")
+	fmt.Println("  songlink-cli history [command] [flags]  List, search, copy (copy <n>), export or clear resolved links")
+	fmt.Println("  songlink-cli availability [flags] <url>  Show platform availability per country")
+	fmt.Println("  songlink-cli resolve [flags]         Resolve by platform ID, ISRC or UPC")
+	fmt.Println("  songlink-cli rewrite [flags] [-]     Replace the music links in text from the clipboard or stdin")
+	fmt.Println("  songlink-cli watch [flags]           Convert music links as they are copied to the clipboard")
+	fmt.Println("  songlink-cli serve [flags]           Serve a local HTTP API")
+	fmt.Println("  songlink-cli card [flags] [<url>]    Make a share card image for a URL or search result")
 	fmt.Println("\nFlags:")
 	fmt.Println("  -x  Return the song.link URL without surrounding <>")
-	fmt.Println("  -d  Return the song.link URL surrounded by <> and the Spotify URL")
-	fmt.Println("  -s  Return only the Spotify URL")
+	fmt.Println("  -d  Return the song.link URL surrounded by <> and the preferred platform URL")
+	fmt.Println("  -s  Return only the preferred platform URL")
+	fmt.Println("  -t=<name>  Named output template: songlink, twitter (-x), discord (-d), links (-s),")
+	fmt.Println("             slack, mastodon, markdown, html, bbcode or one from the config")
+	fmt.Println("  -template=<text>  Custom output template (Go text/template syntax)")
+	fmt.Println("  -no-copy  Print the output to stdout without copying it to the clipboard")
+	fmt.Println("  -o=<format>  Output format: text, json, yaml, csv or tsv (default: text)")
+	fmt.Println("  -platforms=<list>  Comma-separated platforms to include with -x, -d and -s")
+	fmt.Println("                     e.g. -platforms=spotify,tidal,youtubeMusic")
+	fmt.Println("  -prefer=<list>  Platforms to try in order when -platforms isn't set, using the first with a link")
+	fmt.Println("                  (default: spotify,appleMusic,youtubeMusic,youtube)")
+	fmt.Println("  -strict  Exit with an error instead of a warning when platform links are missing")
+	fmt.Println("  -verify=<mode>  Check that each platform link is the same song as the input; ask whether to keep")
+	fmt.Println("                  suspicious links, drop them or keep them (ask, drop or keep; -verify alone asks)")
+	fmt.Println("  -qr  Show the song.link URL as a QR code in the terminal")
+	fmt.Println("  -qr-out=<file.png>  Write the song.link URL as a QR code to a PNG file")
+	fmt.Println("  -album       Resolve the album of a song link instead of the song")
+	fmt.Println("  -album-also  Resolve the album of a song link and output it after the song")
+	fmt.Println("  -country=<code>  Two-letter country code to resolve links for (e.g. US, GB, FI)")
+	fmt.Println("  -keep-locale  Keep the country segment in the song.link URL")
+	fmt.Println("  -no-cache  Don't read or write the cache of resolved links")
+	fmt.Println("  -refresh   Resolve links again and update the cache")
+	fmt.Println("  -api-key=<key>  song.link API key (or $SONGLINK_API_KEY)")
+	fmt.Println("  -api-base-url=<url>  song.link API base URL (or $SONGLINK_API_BASE_URL)")
+	fmt.Println("  -timeout=<duration>  How long to wait for a link to resolve, including retries, 0 to wait indefinitely (default: 30s)")
 	fmt.Println("\nSearch Flags:")
 	fmt.Println("  -type=<type>  Type of search: song, album, or both (default: song)")
+	fmt.Println("  -qr           Show the song.link URL of the selected result as a QR code")
+	fmt.Println("  -qr-out=<file.png>  Write the song.link URL of the selected result as a QR code to a PNG file")
+	fmt.Println("  -limit=<n>    Number of results of each type per page, up to 25 (default: 5)")
+	fmt.Println("  -offset=<n>   Number of results of each type to skip (default: 0)")
+	fmt.Println("                Type n or p at the prompt for the next or previous page")
+	fmt.Println("\nBatch Flags:")
+	fmt.Println("  -workers=<n>  Number of URLs to resolve concurrently (default: 4)")
+	fmt.Println("  -o=<format>   Output format: text, json, yaml, csv or tsv (default: text)")
+	fmt.Println("\nAvailability Flags:")
+	fmt.Println("  -countries=<list>  Comma-separated country codes to check (default: " + defaultAvailabilityCountries + ")")
+	fmt.Println("  -o=<format>        Output format: text, json, yaml, csv or tsv (default: text)")
+	fmt.Println("\nCard Flags:")
+	fmt.Println("  -preset=<name>  Card size: square (1080×1080), story (1080×1920) or landscape (1200×630) (default: square)")
+	fmt.Println("  -out=<file.png>  PNG file to write (default: \"Title — Artist.png\")")
+	fmt.Println("  -search=<query>  Search Apple Music and make a card for the selected result")
+	fmt.Println("  -type=<type>     Type of search: song, album, or both (default: song)")
+	fmt.Println("\nHistory Flags:")
+	fmt.Println("  -q=<text>        Only show entries whose title, artist or links contain the text")
+	fmt.Println("  -since=<time>    Only show entries from a date (2024-05-01) or duration ago (24h, 7d)")
+	fmt.Println("  -until=<time>    Only show entries before a date or duration ago")
+	fmt.Println("  -n=<n>           Number of entries to list, 0 for all (default: 20)")
+	fmt.Println("  -format=<format> Export format: json or csv (default: json)")
+	fmt.Println("\nRewrite Flags:")
+	fmt.Println("  -workers=<n>  Number of links to resolve concurrently (default: 4)")
+	fmt.Println("\nWatch Flags:")
+	fmt.Println("  -interval=<duration>  How often to check the clipboard (default: 1s)")
+	fmt.Println("  -debounce=<duration>  How long a copied link must stay on the clipboard before it is converted (default: 500ms)")
+	fmt.Println("  -ignore=<list>        Comma-separated domains whose links are never converted")
+	fmt.Println("  -install-unit         Write a systemd user unit that runs the watcher")
+	fmt.Println("\nServe Flags:")
+	fmt.Println("  -addr=<address>         Address to listen on (default: localhost:8080); :8080 also accepts other machines")
+	fmt.Println("  -rate=<n>               Requests per minute allowed from each client, 0 for no limit (default: 60)")
+	fmt.Println("  -out=<dir>              Output directory for downloads (default: downloads)")
+	fmt.Println("  -download-workers=<n>   Number of downloads to run at the same time (default: 2)")
+	fmt.Println("\nResolve Flags:")
+	fmt.Println("  -platform=<name> -id=<id>  Resolve the ID of a song or album on a platform")
+	fmt.Println("  -type=<type>  Type of the ID: song or album (default: song)")
+	fmt.Println("  -isrc=<code>  Look up a song by ISRC in the Apple Music catalog")
+	fmt.Println("  -upc=<code>   Look up an album by UPC in the Apple Music catalog")
 }
 
-func loadingIndicator(stop chan bool) {
+// loadingIndicator spins on w until stop receives
+func loadingIndicator(w io.Writer, stop chan bool) {
 	chars := []string{"-", "\\", "|", "/"}
 	i := 0
 	for {
 		select {
 		case <-stop:
-			fmt.Print("\r")
+			// Clear the indicator so that it doesn't run into the next line of output
+			fmt.Fprint(w, "\r", strings.Repeat(" ", len("Loading -")), "\r")
 			return
 		default:
-			fmt.Printf("\rLoading %s", chars[i])
+			fmt.Fprintf(w, "\rLoading %s", chars[i])
 			i = (i + 1) % len(chars)
 			time.Sleep(100 * time.Millisecond)
 		}
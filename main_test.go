@@ -0,0 +1,31 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestMain points the home directory at a temporary one so that tests never read or write the
+// user's config, cache or rate limiter state. Rate limiting and caching are disabled there so
+// that tests using mock servers run quickly and independently.
+func TestMain(m *testing.M) {
+	home, err := os.MkdirTemp("", "songlink-cli-test-*")
+	if err != nil {
+		panic(err)
+	}
+	os.Setenv("HOME", home)
+
+	configDir := filepath.Join(home, ".songlink-cli")
+	if err := os.MkdirAll(configDir, 0700); err != nil {
+		panic(err)
+	}
+	config := []byte(`{"rate_limit": -1, "cache_ttl": "0"}`)
+	if err := os.WriteFile(filepath.Join(configDir, "config.json"), config, 0600); err != nil {
+		panic(err)
+	}
+
+	code := m.Run()
+	os.RemoveAll(home)
+	os.Exit(code)
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/marcusziade/songlink-cli.git/pkg/songlink"
+	"gopkg.in/yaml.v3"
+)
+
+// OutputFormat selects how resolved links are written to stdout
+type OutputFormat string
+
+const (
+	FormatText OutputFormat = "text"
+	FormatJSON OutputFormat = "json"
+	FormatYAML OutputFormat = "yaml"
+	FormatCSV  OutputFormat = "csv"
+	FormatTSV  OutputFormat = "tsv"
+)
+
+// ParseOutputFormat validates the value of the -o flag
+func ParseOutputFormat(value string) (OutputFormat, error) {
+	switch format := OutputFormat(strings.ToLower(strings.TrimSpace(value))); format {
+	case "":
+		return FormatText, nil
+	case FormatText, FormatJSON, FormatYAML, FormatCSV, FormatTSV:
+		return format, nil
+	default:
+		return "", fmt.Errorf("unknown output format %q (valid formats: text, json, yaml, csv, tsv)", value)
+	}
+}
+
+// LinkResult is the schema of the structured output formats.
+// Field names are part of the CLI's public interface; add fields, don't rename them.
+type LinkResult struct {
+	InputURL     string         `json:"input_url" yaml:"input_url"`
+	PageURL      string         `json:"page_url" yaml:"page_url"`
+	Title        string         `json:"title" yaml:"title"`
+	Artist       string         `json:"artist" yaml:"artist"`
+	Type         string         `json:"type" yaml:"type"`
+	ThumbnailURL string         `json:"thumbnail_url" yaml:"thumbnail_url"`
+	Links        []PlatformLink `json:"links" yaml:"links"`
+	// Error is set instead of the other fields when a batch entry couldn't be resolved
+	Error string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// PlatformLink is the link to a song or album on a single platform
+type PlatformLink struct {
+	Platform string `json:"platform" yaml:"platform"`
+	URL      string `json:"url" yaml:"url"`
+}
+
+// NewLinkResult builds the output record for a song.link response.
+// Links are listed in the order of platforms; nil platforms includes every platform in the response.
+// The country segment of the page URL is removed unless keepLocale is set.
+func NewLinkResult(inputURL string, response *songlink.Response, platforms []string, keepLocale bool) LinkResult {
+	if platforms == nil {
+		platforms = songlink.Platforms
+	}
+
+	result := LinkResult{
+		InputURL: inputURL,
+		PageURL:  response.PageURL,
+		Links:    []PlatformLink{},
+	}
+	if !keepLocale {
+		result.PageURL = songlink.StripLocale(response.PageURL)
+	}
+	if entity := response.Entity(); entity != nil {
+		result.Title = entity.Title
+		result.Artist = entity.ArtistName
+		result.Type = entity.Type
+		result.ThumbnailURL = entity.ThumbnailURL
+	}
+	for _, platform := range platforms {
+		if link, ok := response.LinksByPlatform[platform]; ok && link.URL != "" {
+			result.Links = append(result.Links, PlatformLink{Platform: platform, URL: link.URL})
+		}
+	}
+
+	return result
+}
+
+// Link returns the URL for platform, or an empty string if the result has no link for it
+func (r LinkResult) Link(platform string) string {
+	for _, link := range r.Links {
+		if link.Platform == platform {
+			return link.URL
+		}
+	}
+	return ""
+}
+
+// WriteResult writes a single result as a JSON or YAML document, or as a CSV/TSV table with one row.
+// columns are the platforms to include as CSV/TSV columns; nil includes every platform.
+func WriteResult(w io.Writer, format OutputFormat, result LinkResult, columns []string) error {
+	switch format {
+	case FormatJSON:
+		return writeJSON(w, result)
+	case FormatYAML:
+		return writeYAML(w, result)
+	case FormatCSV:
+		return writeTable(w, ',', []LinkResult{result}, columns, false)
+	case FormatTSV:
+		return writeTable(w, '\t', []LinkResult{result}, columns, false)
+	default:
+		return fmt.Errorf("output format %q is not a structured format", format)
+	}
+}
+
+// WriteResults writes results as a JSON array, a YAML sequence or a CSV/TSV table.
+// columns are the platforms to include as CSV/TSV columns; nil includes every platform.
+func WriteResults(w io.Writer, format OutputFormat, results []LinkResult, columns []string) error {
+	return writeResults(w, format, results, columns, false)
+}
+
+// writeBatchResults writes batch results like WriteResults, except that tables end with an error column
+// for the entries that couldn't be resolved
+func writeBatchResults(w io.Writer, format OutputFormat, results []LinkResult, columns []string) error {
+	return writeResults(w, format, results, columns, true)
+}
+
+func writeResults(w io.Writer, format OutputFormat, results []LinkResult, columns []string, withErrors bool) error {
+	switch format {
+	case FormatJSON:
+		return writeJSON(w, results)
+	case FormatYAML:
+		return writeYAML(w, results)
+	case FormatCSV:
+		return writeTable(w, ',', results, columns, withErrors)
+	case FormatTSV:
+		return writeTable(w, '\t', results, columns, withErrors)
+	default:
+		return fmt.Errorf("output format %q is not a structured format", format)
+	}
+}
+
+func writeJSON(w io.Writer, v interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(v); err != nil {
+		return fmt.Errorf("error encoding JSON output: %w", err)
+	}
+	return nil
+}
+
+func writeYAML(w io.Writer, v interface{}) error {
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(v); err != nil {
+		return fmt.Errorf("error encoding YAML output: %w", err)
+	}
+	return encoder.Close()
+}
+
+// writeTable writes one row per result with a column for each platform link, followed by an error column
+// if withErrors is set
+func writeTable(w io.Writer, separator rune, results []LinkResult, columns []string, withErrors bool) error {
+	if columns == nil {
+		columns = songlink.Platforms
+	}
+
+	writer := csv.NewWriter(w)
+	writer.Comma = separator
+
+	header := append([]string{"input_url", "page_url", "title", "artist", "type", "thumbnail_url"}, columns...)
+	if withErrors {
+		header = append(header, "error")
+	}
+	if err := writer.Write(header); err != nil {
+		return fmt.Errorf("error writing table header: %w", err)
+	}
+	for _, result := range results {
+		row := []string{result.InputURL, result.PageURL, result.Title, result.Artist, result.Type, result.ThumbnailURL}
+		for _, platform := range columns {
+			row = append(row, result.Link(platform))
+		}
+		if withErrors {
+			row = append(row, result.Error)
+		}
+		if err := writer.Write(row); err != nil {
+			return fmt.Errorf("error writing table row: %w", err)
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func testLinkResult() LinkResult {
+	return LinkResult{
+		InputURL:     "https://music.apple.com/fi/album/caravan/1572919347?i=1572919354",
+		PageURL:      "https://song.link/i/1572919354",
+		Title:        "Caravan",
+		Artist:       "Duke Ellington",
+		Type:         "song",
+		ThumbnailURL: "https://is1-ssl.mzstatic.com/image/thumb/cover.jpg",
+		Links: []PlatformLink{
+			{Platform: "spotify", URL: "https://open.spotify.com/track/2Xtsv7BUMrNodQWH2JPOc0"},
+			{Platform: "tidal", URL: "https://listen.tidal.com/track/186428424"},
+		},
+	}
+}
+
+func TestWriteResultJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteResult(&buf, FormatJSON, testLinkResult(), nil); err != nil {
+		t.Fatalf("WriteResult returned an unexpected error: %v", err)
+	}
+
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("WriteResult produced invalid JSON: %v\n%s", err, buf.String())
+	}
+	for _, key := range []string{"input_url", "page_url", "title", "artist", "type", "thumbnail_url", "links"} {
+		if _, ok := decoded[key]; !ok {
+			t.Errorf("JSON output is missing the %q key", key)
+		}
+	}
+}
+
+func TestWriteResultsTable(t *testing.T) {
+	var buf bytes.Buffer
+	columns := []string{"spotify", "deezer", "tidal"}
+	if err := WriteResults(&buf, FormatTSV, []LinkResult{testLinkResult()}, columns); err != nil {
+		t.Fatalf("WriteResults returned an unexpected error: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines; want a header and one row:\n%s", len(lines), buf.String())
+	}
+	expectedHeader := "input_url\tpage_url\ttitle\tartist\ttype\tthumbnail_url\tspotify\tdeezer\ttidal"
+	if lines[0] != expectedHeader {
+		t.Errorf("header = %q; want %q", lines[0], expectedHeader)
+	}
+	row := strings.Split(lines[1], "\t")
+	if row[6] != "https://open.spotify.com/track/2Xtsv7BUMrNodQWH2JPOc0" || row[7] != "" || row[8] != "https://listen.tidal.com/track/186428424" {
+		t.Errorf("unexpected platform columns: %q", row[6:])
+	}
+
+	buf.Reset()
+	failed := LinkResult{InputURL: "https://example.com/missing", Links: []PlatformLink{}, Error: "not found"}
+	if err := writeBatchResults(&buf, FormatTSV, []LinkResult{testLinkResult(), failed}, columns); err != nil {
+		t.Fatalf("writeBatchResults returned an unexpected error: %v", err)
+	}
+	lines = strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if lines[0] != expectedHeader+"\terror" {
+		t.Errorf("batch header = %q; want %q", lines[0], expectedHeader+"\terror")
+	}
+	if !strings.HasSuffix(lines[2], "\tnot found") {
+		t.Errorf("batch row = %q; want it to end with the error", lines[2])
+	}
+}
+
+func TestWriteResultTable(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteResult(&buf, FormatCSV, testLinkResult(), []string{"spotify"}); err != nil {
+		t.Fatalf("WriteResult returned an unexpected error: %v", err)
+	}
+
+	header := strings.SplitN(buf.String(), "\n", 2)[0]
+	if expected := "input_url,page_url,title,artist,type,thumbnail_url,spotify"; header != expected {
+		t.Errorf("header = %q; want %q", header, expected)
+	}
+}
+
+func TestParseOutputFormat(t *testing.T) {
+	if format, err := ParseOutputFormat("YAML"); err != nil || format != FormatYAML {
+		t.Errorf("ParseOutputFormat(%q) = %q, %v; want %q", "YAML", format, err, FormatYAML)
+	}
+	if _, err := ParseOutputFormat("xml"); err == nil {
+		t.Error("ParseOutputFormat should reject unknown formats")
+	}
+}
@@ -0,0 +1,196 @@
+// Package card renders share cards: images with a release's artwork, title, artist,
+// the platforms it is on and a QR code to its song.link page.
+package card
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"strings"
+
+	qrcode "github.com/skip2/go-qrcode"
+	xdraw "golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+// Card is the content of a share card
+type Card struct {
+	Title  string
+	Artist string
+	// Artwork is the cover image. Without one the card is drawn in neutral colors.
+	Artwork image.Image
+	// Platforms are the names of the platforms the release is on, e.g. "Spotify"
+	Platforms []string
+	// URL is encoded in the QR code, usually the song.link page
+	URL string
+}
+
+// Preset is the size and layout of a card
+type Preset struct {
+	Name          string
+	Width, Height int
+	artwork       image.Rectangle
+	// text is the area the title, artist and platforms are drawn in, from the top
+	text     image.Rectangle
+	qr       image.Rectangle
+	centered bool
+	// titleSize and textSize are font sizes in pixels
+	titleSize, textSize float64
+}
+
+// Presets are the card sizes: square posts, 9:16 stories and landscape link previews
+var Presets = []Preset{
+	{
+		Name: "square", Width: 1080, Height: 1080,
+		artwork:   image.Rect(270, 70, 810, 610),
+		text:      image.Rect(80, 660, 780, 1010),
+		qr:        image.Rect(800, 730, 1010, 940),
+		titleSize: 56, textSize: 36,
+	},
+	{
+		Name: "story", Width: 1080, Height: 1920,
+		artwork:   image.Rect(120, 180, 960, 1020),
+		text:      image.Rect(100, 1090, 980, 1420),
+		qr:        image.Rect(390, 1460, 690, 1760),
+		centered:  true,
+		titleSize: 72, textSize: 44,
+	},
+	{
+		Name: "landscape", Width: 1200, Height: 630,
+		artwork:   image.Rect(60, 60, 570, 570),
+		text:      image.Rect(630, 80, 1140, 400),
+		qr:        image.Rect(630, 400, 800, 570),
+		titleSize: 52, textSize: 32,
+	},
+}
+
+// PresetNames returns the names of the presets, for usage messages
+func PresetNames() []string {
+	names := make([]string, len(Presets))
+	for i, preset := range Presets {
+		names[i] = preset.Name
+	}
+	return names
+}
+
+// LookupPreset returns the preset called name
+func LookupPreset(name string) (Preset, error) {
+	for _, preset := range Presets {
+		if strings.EqualFold(preset.Name, name) {
+			return preset, nil
+		}
+	}
+	return Preset{}, fmt.Errorf("unknown card preset %q (use %s)", name, strings.Join(PresetNames(), ", "))
+}
+
+// Render draws card in the layout of preset
+func Render(card Card, preset Preset) (image.Image, error) {
+	if card.URL == "" {
+		return nil, errors.New("the card needs a URL for the QR code")
+	}
+	theme := NeutralTheme
+	if card.Artwork != nil {
+		theme = ThemeFromImage(card.Artwork)
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, preset.Width, preset.Height))
+	draw.Draw(img, img.Bounds(), image.NewUniform(theme.Background), image.Point{}, draw.Src)
+
+	if card.Artwork != nil {
+		xdraw.CatmullRom.Scale(img, preset.artwork, card.Artwork, card.Artwork.Bounds(), draw.Over, nil)
+	}
+
+	titleFace, err := newFace(gobold.TTF, preset.titleSize)
+	if err != nil {
+		return nil, err
+	}
+	textFace, err := newFace(goregular.TTF, preset.textSize)
+	if err != nil {
+		return nil, err
+	}
+	y := preset.text.Min.Y
+	y = drawText(img, preset, titleFace, theme.Text, card.Title, y, 2)
+	y = drawText(img, preset, textFace, theme.Text, card.Artist, y+int(preset.textSize/3), 1)
+	if len(card.Platforms) > 0 {
+		drawText(img, preset, textFace, theme.Accent, strings.Join(card.Platforms, " · "), y+int(preset.textSize/2), 3)
+	}
+
+	code, err := qrcode.New(card.URL, qrcode.Medium)
+	if err != nil {
+		return nil, fmt.Errorf("error creating QR code: %w", err)
+	}
+	// The code keeps its white quiet zone so that it scans on any background
+	qr := code.Image(preset.qr.Dx())
+	draw.Draw(img, preset.qr, qr, qr.Bounds().Min, draw.Src)
+
+	return img, nil
+}
+
+func newFace(ttf []byte, size float64) (font.Face, error) {
+	parsed, err := opentype.Parse(ttf)
+	if err != nil {
+		return nil, fmt.Errorf("error loading font: %w", err)
+	}
+	return opentype.NewFace(parsed, &opentype.FaceOptions{Size: size, DPI: 72, Hinting: font.HintingFull})
+}
+
+// drawText draws text wrapped to the width of the preset's text area, in at most maxLines lines,
+// starting at the top y. It returns the bottom of the last line.
+func drawText(img draw.Image, preset Preset, face font.Face, c color.Color, text string, y, maxLines int) int {
+	if text == "" {
+		return y
+	}
+	metrics := face.Metrics()
+	lineHeight := (metrics.Ascent + metrics.Descent).Ceil()
+	drawer := &font.Drawer{Dst: img, Src: image.NewUniform(c), Face: face}
+
+	for _, line := range wrap(drawer, text, fixed.I(preset.text.Dx()), maxLines) {
+		if y+lineHeight > preset.text.Max.Y {
+			break
+		}
+		x := fixed.I(preset.text.Min.X)
+		if preset.centered {
+			x += (fixed.I(preset.text.Dx()) - drawer.MeasureString(line)) / 2
+		}
+		drawer.Dot = fixed.Point26_6{X: x, Y: fixed.I(y) + metrics.Ascent}
+		drawer.DrawString(line)
+		y += lineHeight
+	}
+	return y
+}
+
+// wrap splits text into lines no wider than width. Text that doesn't fit in maxLines is cut off with an ellipsis.
+func wrap(drawer *font.Drawer, text string, width fixed.Int26_6, maxLines int) []string {
+	var lines []string
+	line := ""
+	for _, word := range strings.Fields(text) {
+		candidate := strings.TrimSpace(line + " " + word)
+		if line == "" || drawer.MeasureString(candidate) <= width {
+			line = candidate
+			continue
+		}
+		lines = append(lines, line)
+		line = word
+	}
+	lines = append(lines, line)
+
+	if len(lines) > maxLines {
+		lines = lines[:maxLines]
+		lines[maxLines-1] += "…"
+	}
+	// A single word can be wider than the line on its own
+	for i, line := range lines {
+		for drawer.MeasureString(line) > width && len([]rune(line)) > 1 {
+			runes := []rune(strings.TrimSuffix(line, "…"))
+			line = string(runes[:len(runes)-1]) + "…"
+		}
+		lines[i] = line
+	}
+	return lines
+}
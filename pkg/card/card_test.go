@@ -0,0 +1,72 @@
+package card
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+// testArtwork returns a mostly dark blue image with a yellow stripe
+func testArtwork() image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, 300, 300))
+	draw.Draw(img, img.Bounds(), image.NewUniform(color.RGBA{0x10, 0x20, 0x60, 0xff}), image.Point{}, draw.Src)
+	draw.Draw(img, image.Rect(0, 200, 300, 260), image.NewUniform(color.RGBA{0xf0, 0xd0, 0x20, 0xff}), image.Point{}, draw.Src)
+	return img
+}
+
+func TestThemeFromImage(t *testing.T) {
+	theme := ThemeFromImage(testArtwork())
+	if theme.Background != (color.RGBA{0x0a, 0x14, 0x3e, 0xff}) {
+		t.Errorf("Background = %v; want a darker shade of the blue of the artwork", theme.Background)
+	}
+	if theme.Text != (color.RGBA{0xff, 0xff, 0xff, 0xff}) {
+		t.Errorf("Text = %v; want white on a dark background", theme.Text)
+	}
+	if theme.Accent != (color.RGBA{0xf0, 0xd0, 0x20, 0xff}) {
+		t.Errorf("Accent = %v; want the yellow of the artwork", theme.Accent)
+	}
+
+	if theme := ThemeFromImage(image.NewRGBA(image.Rect(0, 0, 0, 0))); theme != NeutralTheme {
+		t.Errorf("ThemeFromImage(empty) = %v; want the neutral theme", theme)
+	}
+
+	light := image.NewRGBA(image.Rect(0, 0, 10, 10))
+	draw.Draw(light, light.Bounds(), image.NewUniform(color.RGBA{0xf0, 0xf0, 0xe0, 0xff}), image.Point{}, draw.Src)
+	if theme := ThemeFromImage(light); theme.Text != (color.RGBA{0x11, 0x11, 0x11, 0xff}) || theme.Accent != theme.Text {
+		t.Errorf("ThemeFromImage(light) = %v; want dark text and accent", theme)
+	}
+}
+
+func TestRender(t *testing.T) {
+	card := Card{
+		Title:     "Caravan (Live at the Newport Jazz Festival, July 7, 1956)",
+		Artist:    "Duke Ellington & His Orchestra",
+		Artwork:   testArtwork(),
+		Platforms: []string{"Spotify", "Apple Music", "Tidal"},
+		URL:       "https://song.link/i/1572919354",
+	}
+	for _, preset := range Presets {
+		img, err := Render(card, preset)
+		if err != nil {
+			t.Fatalf("Render(%s) returned an unexpected error: %v", preset.Name, err)
+		}
+		if bounds := img.Bounds(); bounds.Dx() != preset.Width || bounds.Dy() != preset.Height {
+			t.Errorf("Render(%s) is %dx%d; want %dx%d", preset.Name, bounds.Dx(), bounds.Dy(), preset.Width, preset.Height)
+		}
+		// The corner shows the background and the QR code keeps its white quiet zone
+		if c := color.RGBAModel.Convert(img.At(0, 0)); c != (color.RGBA{0x0a, 0x14, 0x3e, 0xff}) {
+			t.Errorf("Render(%s) corner = %v; want the theme background", preset.Name, c)
+		}
+		if c := color.RGBAModel.Convert(img.At(preset.qr.Min.X+1, preset.qr.Min.Y+1)); c != (color.RGBA{0xff, 0xff, 0xff, 0xff}) {
+			t.Errorf("Render(%s) QR corner = %v; want white", preset.Name, c)
+		}
+	}
+
+	if _, err := Render(Card{Title: "Caravan"}, Presets[0]); err == nil {
+		t.Error("Render should fail without a URL")
+	}
+	if _, err := LookupPreset("banner"); err == nil {
+		t.Error("LookupPreset should reject unknown presets")
+	}
+}
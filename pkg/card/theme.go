@@ -0,0 +1,109 @@
+package card
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// Theme is the colors of a card
+type Theme struct {
+	Background color.RGBA
+	Text       color.RGBA
+	// Accent is used for the platform list
+	Accent color.RGBA
+}
+
+// NeutralTheme is used for cards without artwork
+var NeutralTheme = Theme{
+	Background: color.RGBA{0x1c, 0x1c, 0x1e, 0xff},
+	Text:       color.RGBA{0xff, 0xff, 0xff, 0xff},
+	Accent:     color.RGBA{0xa1, 0xa1, 0xaa, 0xff},
+}
+
+// ThemeFromImage picks the card colors from artwork: the background is a darker shade of its most common color,
+// the accent the most common one that stands out from the background, and the text black or white,
+// whichever contrasts more
+func ThemeFromImage(artwork image.Image) Theme {
+	type bucket struct {
+		key, r, g, b, n int
+	}
+	buckets := make(map[int]*bucket)
+
+	// Sample about 64×64 pixels and group them by their top four bits per channel
+	bounds := artwork.Bounds()
+	step := max(1, max(bounds.Dx(), bounds.Dy())/64)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y += step {
+		for x := bounds.Min.X; x < bounds.Max.X; x += step {
+			c := color.RGBAModel.Convert(artwork.At(x, y)).(color.RGBA)
+			key := int(c.R>>4)<<8 | int(c.G>>4)<<4 | int(c.B>>4)
+			if buckets[key] == nil {
+				buckets[key] = &bucket{key: key}
+			}
+			b := buckets[key]
+			b.r, b.g, b.b, b.n = b.r+int(c.R), b.g+int(c.G), b.b+int(c.B), b.n+1
+		}
+	}
+	average := func(b *bucket) color.RGBA {
+		return color.RGBA{uint8(b.r / b.n), uint8(b.g / b.n), uint8(b.b / b.n), 0xff}
+	}
+
+	// Ties are broken by key so that the same artwork always gets the same theme
+	more := func(a, b *bucket) bool {
+		return b == nil || a.n > b.n || (a.n == b.n && a.key < b.key)
+	}
+
+	var background *bucket
+	for _, b := range buckets {
+		if more(b, background) {
+			background = b
+		}
+	}
+	if background == nil {
+		return NeutralTheme
+	}
+
+	// The background is darkened so that the cover stands out from it
+	dominant := average(background)
+	theme := Theme{Background: color.RGBA{shade(dominant.R), shade(dominant.G), shade(dominant.B), 0xff}}
+	white, black := color.RGBA{0xff, 0xff, 0xff, 0xff}, color.RGBA{0x11, 0x11, 0x11, 0xff}
+	theme.Text = white
+	if contrast(black, theme.Background) > contrast(white, theme.Background) {
+		theme.Text = black
+	}
+
+	var accent *bucket
+	for _, b := range buckets {
+		if contrast(average(b), theme.Background) >= 3 && more(b, accent) {
+			accent = b
+		}
+	}
+	theme.Accent = theme.Text
+	if accent != nil {
+		theme.Accent = average(accent)
+	}
+	return theme
+}
+
+// luminance returns the relative luminance of c as defined by WCAG, from 0 for black to 1 for white
+func luminance(c color.RGBA) float64 {
+	linear := func(v uint8) float64 {
+		s := float64(v) / 255
+		if s <= 0.03928 {
+			return s / 12.92
+		}
+		return math.Pow((s+0.055)/1.055, 2.4)
+	}
+	return 0.2126*linear(c.R) + 0.7152*linear(c.G) + 0.0722*linear(c.B)
+}
+
+// contrast returns the WCAG contrast ratio of a and b, from 1 to 21
+func contrast(a, b color.RGBA) float64 {
+	la, lb := luminance(a), luminance(b)
+	return (math.Max(la, lb) + 0.05) / (math.Min(la, lb) + 0.05)
+}
+
+// shade darkens a color channel to 65%
+func shade(v uint8) uint8 {
+	return uint8(int(v) * 65 / 100)
+}
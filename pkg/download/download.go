@@ -0,0 +1,207 @@
+// Package download saves songs as MP3 files, or MP4 videos with the artwork, using yt-dlp and ffmpeg.
+package download
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Request describes the song to download
+type Request struct {
+	Song   string
+	Artist string
+	// ArtworkURL is the cover image of MP4 videos
+	ArtworkURL string
+	// Format is "mp3" or "mp4"
+	Format string
+	// OutDir is the directory the file is saved in. It is created if needed.
+	OutDir string
+}
+
+// Downloader downloads songs by searching YouTube with yt-dlp
+type Downloader struct {
+	httpClient *http.Client
+	// stdout and stderr receive the output of yt-dlp and ffmpeg
+	stdout io.Writer
+	stderr io.Writer
+}
+
+// Option configures a Downloader
+type Option func(*Downloader)
+
+// WithHTTPClient sets the HTTP client used to download artwork
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(d *Downloader) {
+		d.httpClient = httpClient
+	}
+}
+
+// WithOutput shows the output of yt-dlp and ffmpeg on stdout and stderr. By default it is discarded.
+func WithOutput(stdout, stderr io.Writer) Option {
+	return func(d *Downloader) {
+		d.stdout = stdout
+		d.stderr = stderr
+	}
+}
+
+// New creates a Downloader
+func New(options ...Option) *Downloader {
+	d := &Downloader{httpClient: http.DefaultClient, stdout: io.Discard, stderr: io.Discard}
+	for _, option := range options {
+		option(d)
+	}
+	return d
+}
+
+// Download downloads the song, converting to MP3 or creating an MP4 with the artwork,
+// and returns the path where the file was saved
+func (d *Downloader) Download(ctx context.Context, request Request) (string, error) {
+	// Ensure yt-dlp is available
+	if _, err := exec.LookPath("yt-dlp"); err != nil {
+		return "", fmt.Errorf("yt-dlp not found in PATH: %w", err)
+	}
+	baseName := SanitizeFileName(fmt.Sprintf("%s - %s", request.Artist, request.Song))
+	if err := os.MkdirAll(request.OutDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create output directory: %w", err)
+	}
+	searchTerm := fmt.Sprintf("ytsearch1:%s %s official audio", request.Song, request.Artist)
+
+	switch strings.ToLower(request.Format) {
+	case "mp3":
+		// Extract audio as MP3 with embedded thumbnail
+		outputTemplate := filepath.Join(request.OutDir, baseName+".%(ext)s")
+		err := d.run(ctx, "yt-dlp",
+			searchTerm,
+			"--extract-audio",
+			"--audio-format", "mp3",
+			"--embed-thumbnail",
+			"--add-metadata",
+			"--output", outputTemplate,
+		)
+		if err != nil {
+			if ctx.Err() != nil {
+				removePartial(request.OutDir, baseName)
+			}
+			return "", err
+		}
+		return filepath.Join(request.OutDir, baseName+".mp3"), nil
+	case "mp4":
+		if _, err := exec.LookPath("ffmpeg"); err != nil {
+			return "", fmt.Errorf("ffmpeg not found in PATH: %w", err)
+		}
+		tempDir, err := os.MkdirTemp("", "songdl-*")
+		if err != nil {
+			return "", fmt.Errorf("failed to create temp dir: %w", err)
+		}
+		defer os.RemoveAll(tempDir)
+
+		artPath := filepath.Join(tempDir, "cover.jpg")
+		if err := d.downloadFile(ctx, artPath, request.ArtworkURL); err != nil {
+			return "", fmt.Errorf("failed to download artwork: %w", err)
+		}
+
+		// Download best audio
+		audioTemplate := filepath.Join(tempDir, "temp_audio.%(ext)s")
+		if err := d.run(ctx, "yt-dlp", searchTerm, "-f", "bestaudio", "--output", audioTemplate); err != nil {
+			return "", fmt.Errorf("audio download failed: %w", err)
+		}
+		entries, err := os.ReadDir(tempDir)
+		if err != nil {
+			return "", fmt.Errorf("failed to read temp dir: %w", err)
+		}
+		var audioFile string
+		for _, e := range entries {
+			if strings.HasPrefix(e.Name(), "temp_audio") {
+				audioFile = filepath.Join(tempDir, e.Name())
+				break
+			}
+		}
+		if audioFile == "" {
+			return "", fmt.Errorf("audio file not found in temp dir")
+		}
+
+		// Build output video
+		outPath := filepath.Join(request.OutDir, baseName+".mp4")
+		err = d.run(ctx, "ffmpeg",
+			"-y",
+			"-loop", "1",
+			"-i", artPath,
+			"-i", audioFile,
+			"-c:v", "libx264",
+			"-tune", "stillimage",
+			"-c:a", "aac",
+			"-b:a", "192k",
+			"-pix_fmt", "yuv420p",
+			"-shortest",
+			outPath,
+		)
+		if err != nil {
+			// ffmpeg leaves the video it was writing behind
+			os.Remove(outPath)
+			return "", fmt.Errorf("video creation failed: %w", err)
+		}
+		return outPath, nil
+	default:
+		return "", fmt.Errorf("unsupported format: %s", request.Format)
+	}
+}
+
+// removePartial removes the unfinished files yt-dlp leaves in dir when it is stopped while downloading baseName
+func removePartial(dir, baseName string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, e := range entries {
+		name := e.Name()
+		if strings.HasPrefix(name, baseName+".") && (strings.HasSuffix(name, ".part") || strings.HasSuffix(name, ".ytdl")) {
+			os.Remove(filepath.Join(dir, name))
+		}
+	}
+}
+
+// run runs an external command, sending its output to the downloader's output
+func (d *Downloader) run(ctx context.Context, name string, args ...string) error {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdout = d.stdout
+	cmd.Stderr = d.stderr
+	return cmd.Run()
+}
+
+// downloadFile fetches a URL and writes it to the specified path
+func (d *Downloader) downloadFile(ctx context.Context, path, url string) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := d.httpClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("bad status downloading %s: %s", url, resp.Status)
+	}
+	out, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	_, err = io.Copy(out, resp.Body)
+	return err
+}
+
+// invalidFileNameChars are characters that aren't allowed in file names on common file systems
+var invalidFileNameChars = regexp.MustCompile(`[\\/:*?"<>|]`)
+
+// SanitizeFileName replaces invalid filename characters
+func SanitizeFileName(name string) string {
+	return invalidFileNameChars.ReplaceAllString(name, "_")
+}
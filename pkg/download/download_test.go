@@ -0,0 +1,38 @@
+package download
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSanitizeFileName(t *testing.T) {
+	name := SanitizeFileName(`AC/DC - Who Made Who? "Live": <1986>|*\`)
+	if expected := "AC_DC - Who Made Who_ _Live__ _1986____"; name != expected {
+		t.Errorf("SanitizeFileName() = %q; want %q", name, expected)
+	}
+}
+
+func TestRemovePartial(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"Prince - Purple Rain.webm.part", "Prince - Purple Rain.webm.ytdl", "Prince - Purple Rain.mp3", "Prince - When Doves Cry.webm.part"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	removePartial(dir, "Prince - Purple Rain")
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	expected := []string{"Prince - Purple Rain.mp3", "Prince - When Doves Cry.webm.part"}
+	if len(names) != len(expected) || names[0] != expected[0] || names[1] != expected[1] {
+		t.Errorf("files left = %q; want %q", names, expected)
+	}
+}
@@ -0,0 +1,241 @@
+// Package search finds songs and albums in the Apple Music catalog.
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/marcusziade/musickitkat"
+	"github.com/marcusziade/musickitkat/auth"
+	"github.com/marcusziade/musickitkat/client"
+	"github.com/marcusziade/musickitkat/models"
+	"github.com/marcusziade/musickitkat/services"
+)
+
+// Type is the kind of catalog item to search for
+type Type string
+
+const (
+	Song  Type = "song"
+	Album Type = "album"
+	Both  Type = "both"
+)
+
+// Result is a song or album found in the catalog
+type Result struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	ArtistName string `json:"artist"`
+	Type       Type   `json:"type"`
+	URL        string `json:"url"`
+	// ArtworkURL is the URL of the artwork image, 500x500
+	ArtworkURL string `json:"artwork_url"`
+}
+
+// Credentials are the Apple Developer credentials used to sign the developer token
+type Credentials struct {
+	TeamID     string
+	KeyID      string
+	PrivateKey string
+	MusicID    string
+}
+
+// Client searches the Apple Music catalog
+type Client struct {
+	// api makes the catalog requests the SDK has no method for, such as lookups by ISRC or UPC
+	api    *client.Client
+	search *services.SearchService
+}
+
+// Option configures a Client
+type Option func(*[]client.ClientOption)
+
+// WithHTTPClient sets the HTTP client used for catalog requests
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(options *[]client.ClientOption) {
+		*options = append(*options, client.WithHTTPClient(httpClient))
+	}
+}
+
+// WithBaseURL sets the Apple Music API endpoint, e.g. to use a mock server
+func WithBaseURL(baseURL string) Option {
+	return func(options *[]client.ClientOption) {
+		*options = append(*options, client.WithBaseURL(baseURL))
+	}
+}
+
+// NewClient creates a client signing its requests with a developer token made from credentials
+func NewClient(credentials Credentials, options ...Option) (*Client, error) {
+	if credentials.TeamID == "" || credentials.KeyID == "" || credentials.PrivateKey == "" {
+		return nil, errors.New("apple music api credentials not configured")
+	}
+
+	developerToken, err := auth.NewDeveloperToken(
+		credentials.TeamID,
+		credentials.KeyID,
+		[]byte(credentials.PrivateKey),
+		credentials.MusicID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create developer token: %w", err)
+	}
+
+	var clientOptions []client.ClientOption
+	for _, option := range options {
+		option(&clientOptions)
+	}
+	api := client.NewClient(clientOptions...)
+	api.SetDeveloperToken(developerToken.String())
+
+	return &Client{
+		api:    api,
+		search: services.NewSearchService(api),
+	}, nil
+}
+
+// DefaultLimit is how many results of each type the Apple Music API returns per page unless asked for more
+const DefaultLimit = 5
+
+// MaxLimit is the most results of each type the Apple Music API returns per page
+const MaxLimit = 25
+
+// Page is one page of search results
+type Page struct {
+	Results []Result
+	// More reports whether the catalog has results after this page
+	More bool
+}
+
+// Search searches for music by query and type, returning the first page of results
+func (c *Client) Search(ctx context.Context, query string, searchType Type) ([]Result, error) {
+	page, err := c.SearchPage(ctx, query, searchType, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	return page.Results, nil
+}
+
+// SearchPage searches for music by query and type, returning up to limit results of each type
+// starting at offset. A limit of 0 uses DefaultLimit.
+func (c *Client) SearchPage(ctx context.Context, query string, searchType Type, limit, offset int) (Page, error) {
+	if limit < 0 || limit > MaxLimit {
+		return Page{}, fmt.Errorf("invalid limit %d (use 1 to %d)", limit, MaxLimit)
+	}
+	if offset < 0 {
+		return Page{}, fmt.Errorf("invalid offset %d", offset)
+	}
+
+	var page Page
+	var searchTypes []string
+	songs, albums := string(musickitkat.SearchTypesSongs), string(musickitkat.SearchTypesAlbums)
+
+	switch searchType {
+	case Album:
+		searchTypes = []string{albums}
+	case Both:
+		searchTypes = []string{songs, albums}
+	default:
+		// Default to songs if type is invalid
+		searchTypes = []string{songs}
+	}
+
+	options := &models.SearchOptions{Limit: limit, Offset: offset}
+	for _, st := range searchTypes {
+		searchResults, err := c.search.Search(ctx, query, []string{st}, options)
+		if err != nil {
+			return Page{}, fmt.Errorf("failed to search %s: %w", st, err)
+		}
+
+		if st == songs {
+			for _, song := range searchResults.Results.Songs.Data {
+				page.Results = append(page.Results, songResult(song))
+			}
+			page.More = page.More || searchResults.Results.Songs.Next != ""
+		} else {
+			for _, album := range searchResults.Results.Albums.Data {
+				page.Results = append(page.Results, albumResult(album))
+			}
+			page.More = page.More || searchResults.Results.Albums.Next != ""
+		}
+	}
+
+	return page, nil
+}
+
+// LookupISRC finds the song with an ISRC in the catalog of storefront
+func (c *Client) LookupISRC(ctx context.Context, isrc, storefront string) (*Result, error) {
+	path := fmt.Sprintf("catalog/%s/songs?%s", storefront, url.Values{"filter[isrc]": {isrc}}.Encode())
+
+	var response models.SongsResponse
+	if err := c.api.Get(ctx, path, &response); err != nil {
+		return nil, fmt.Errorf("failed to look up ISRC %s: %w", isrc, err)
+	}
+	if len(response.Data) == 0 {
+		return nil, fmt.Errorf("no song found with ISRC %s", isrc)
+	}
+
+	result := songResult(response.Data[0])
+	return &result, nil
+}
+
+// LookupUPC finds the album with a UPC in the catalog of storefront
+func (c *Client) LookupUPC(ctx context.Context, upc, storefront string) (*Result, error) {
+	path := fmt.Sprintf("catalog/%s/albums?%s", storefront, url.Values{"filter[upc]": {upc}}.Encode())
+
+	var response models.AlbumsResponse
+	if err := c.api.Get(ctx, path, &response); err != nil {
+		return nil, fmt.Errorf("failed to look up UPC %s: %w", upc, err)
+	}
+	if len(response.Data) == 0 {
+		return nil, fmt.Errorf("no album found with UPC %s", upc)
+	}
+
+	result := albumResult(response.Data[0])
+	return &result, nil
+}
+
+// LookupSongAlbum finds the album a song with an Apple Music ID belongs to in the catalog of storefront
+func (c *Client) LookupSongAlbum(ctx context.Context, songID, storefront string) (*Result, error) {
+	path := fmt.Sprintf("catalog/%s/songs/%s/albums", storefront, url.PathEscape(songID))
+	var response models.AlbumsResponse
+	if err := c.api.Get(ctx, path, &response); err != nil {
+		return nil, fmt.Errorf("failed to look up the album of song %s: %w", songID, err)
+	}
+	if len(response.Data) == 0 {
+		return nil, fmt.Errorf("no album found for song %s", songID)
+	}
+	result := albumResult(response.Data[0])
+	return &result, nil
+}
+
+func songResult(song models.Song) Result {
+	return Result{
+		ID:         song.ID,
+		Name:       song.Attributes.Name,
+		ArtistName: song.Attributes.ArtistName,
+		Type:       Song,
+		URL:        song.Attributes.URL,
+		ArtworkURL: artworkURL(song.Attributes.Artwork.URL),
+	}
+}
+
+func albumResult(album models.Album) Result {
+	return Result{
+		ID:         album.ID,
+		Name:       album.Attributes.Name,
+		ArtistName: album.Attributes.ArtistName,
+		Type:       Album,
+		URL:        album.Attributes.URL,
+		ArtworkURL: artworkURL(album.Attributes.Artwork.URL),
+	}
+}
+
+// artworkURL fills in the size of an artwork URL template
+func artworkURL(template string) string {
+	template = strings.ReplaceAll(template, "{w}", "500")
+	return strings.ReplaceAll(template, "{h}", "500")
+}
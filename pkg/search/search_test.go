@@ -0,0 +1,107 @@
+package search
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// testCredentials returns credentials with a freshly generated signing key
+func testCredentials(t *testing.T) Credentials {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to marshal key: %v", err)
+	}
+	privateKey := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	return Credentials{TeamID: "TEAM", KeyID: "KEY", PrivateKey: string(privateKey), MusicID: "TEAM"}
+}
+
+func TestSearchAndLookup(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") {
+			t.Errorf("request without a developer token: %s", r.URL)
+		}
+		song := `{"id": "1572919354", "type": "songs", "attributes": {"name": "Caravan", "artistName": "Duke Ellington",
+			"url": "https://music.apple.com/us/album/caravan/1572919347?i=1572919354", "artwork": {"url": "https://example.com/{w}x{h}.jpg"}}}`
+		switch {
+		case r.URL.Path == "/v1/catalog/us/search" && r.URL.Query().Get("term") == "caravan":
+			fmt.Fprintf(w, `{"results": {"songs": {"data": [%s]}}}`, song)
+		case r.URL.Path == "/v1/catalog/us/search" && r.URL.Query().Get("term") == "ellington":
+			if r.URL.Query().Get("limit") != "1" || r.URL.Query().Get("offset") != "3" {
+				t.Errorf("page requested with %s; want limit 1 and offset 3", r.URL.RawQuery)
+			}
+			fmt.Fprintf(w, `{"results": {"songs": {"data": [%s], "next": "/v1/catalog/us/search?offset=4"}}}`, song)
+		case r.URL.Path == "/v1/catalog/gb/songs" && r.URL.Query().Get("filter[isrc]") == "USSM10001234":
+			fmt.Fprintf(w, `{"data": [%s]}`, song)
+		case r.URL.Path == "/v1/catalog/gb/albums":
+			fmt.Fprint(w, `{"data": []}`)
+		case r.URL.Path == "/v1/catalog/us/songs/1572919354/albums":
+			fmt.Fprint(w, `{"data": [{"id": "1572919347", "type": "albums", "attributes": {"name": "Ellington at Newport",
+				"artistName": "Duke Ellington", "url": "https://music.apple.com/us/album/ellington-at-newport/1572919347"}}]}`)
+		default:
+			t.Errorf("unexpected request to the mock server: %s", r.URL)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewClient(testCredentials(t), WithBaseURL(server.URL))
+	if err != nil {
+		t.Fatalf("NewClient returned an unexpected error: %v", err)
+	}
+
+	results, err := client.Search(context.Background(), "caravan", Song)
+	if err != nil {
+		t.Fatalf("Search returned an unexpected error: %v", err)
+	}
+	expected := Result{
+		ID:         "1572919354",
+		Name:       "Caravan",
+		ArtistName: "Duke Ellington",
+		Type:       Song,
+		URL:        "https://music.apple.com/us/album/caravan/1572919347?i=1572919354",
+		ArtworkURL: "https://example.com/500x500.jpg",
+	}
+	if len(results) != 1 || results[0] != expected {
+		t.Errorf("Search() = %+v; want [%+v]", results, expected)
+	}
+
+	page, err := client.SearchPage(context.Background(), "ellington", Song, 1, 3)
+	if err != nil || len(page.Results) != 1 || !page.More {
+		t.Errorf("SearchPage() = %+v, %v; want one result and more to come", page, err)
+	}
+	if _, err := client.SearchPage(context.Background(), "ellington", Song, MaxLimit+1, 0); err == nil {
+		t.Errorf("SearchPage should reject limits over %d", MaxLimit)
+	}
+
+	result, err := client.LookupISRC(context.Background(), "USSM10001234", "gb")
+	if err != nil || *result != expected {
+		t.Errorf("LookupISRC() = %+v, %v; want %+v", result, err, expected)
+	}
+	if _, err := client.LookupUPC(context.Background(), "00602577014342", "gb"); err == nil {
+		t.Error("LookupUPC should fail when the catalog has no album with the UPC")
+	}
+
+	album, err := client.LookupSongAlbum(context.Background(), "1572919354", "us")
+	if err != nil || album.ID != "1572919347" || album.Type != Album || album.URL != "https://music.apple.com/us/album/ellington-at-newport/1572919347" {
+		t.Errorf("LookupSongAlbum() = %+v, %v; want the album 1572919347", album, err)
+	}
+}
+
+func TestNewClientRequiresCredentials(t *testing.T) {
+	if _, err := NewClient(Credentials{TeamID: "TEAM"}); err == nil {
+		t.Error("NewClient should fail without a key")
+	}
+}
@@ -0,0 +1,272 @@
+package songlink
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultBaseURL is the public song.link API endpoint
+const DefaultBaseURL = "https://api.song.link/v1-alpha.1"
+
+const (
+	// DefaultMaxRetries is how many times a request is retried after a 429 or 5xx response
+	DefaultMaxRetries = 4
+	// baseRetryDelay and maxRetryDelay bound the exponential backoff between retries
+	baseRetryDelay = time.Second
+	maxRetryDelay  = 30 * time.Second
+	// maxRetryAfter is the longest Retry-After that is waited out instead of failing the request
+	maxRetryAfter = 2 * time.Minute
+)
+
+// Limiter is waited on before every request, e.g. to stay within the API quota
+type Limiter interface {
+	Wait(ctx context.Context) error
+}
+
+// Client makes requests to the song.link API. The zero value isn't usable; create clients with NewClient.
+type Client struct {
+	httpClient *http.Client
+	baseURL    string
+	apiKey     string
+	limiter    Limiter
+	maxRetries int
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithHTTPClient sets the HTTP client used for API requests and for expanding short links
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithBaseURL sets the API endpoint, e.g. to use a proxy or a mock server
+func WithBaseURL(baseURL string) Option {
+	return func(c *Client) {
+		c.baseURL = baseURL
+	}
+}
+
+// WithAPIKey sets the API key sent with every request
+func WithAPIKey(key string) Option {
+	return func(c *Client) {
+		c.apiKey = key
+	}
+}
+
+// WithLimiter sets a limiter that is waited on before every request, including retries
+func WithLimiter(limiter Limiter) Option {
+	return func(c *Client) {
+		c.limiter = limiter
+	}
+}
+
+// WithMaxRetries sets how many times a request is retried after a 429 or 5xx response
+func WithMaxRetries(retries int) Option {
+	return func(c *Client) {
+		c.maxRetries = retries
+	}
+}
+
+// NewClient creates a client for the public API endpoint using http.DefaultClient unless options say otherwise
+func NewClient(options ...Option) *Client {
+	c := &Client{
+		httpClient: http.DefaultClient,
+		baseURL:    DefaultBaseURL,
+		maxRetries: DefaultMaxRetries,
+	}
+	for _, option := range options {
+		option(c)
+	}
+	return c
+}
+
+// Links resolves query. Requests are retried with backoff on 429 and 5xx responses, honoring Retry-After.
+// Errors match ErrTimeout when ctx's deadline passes. Otherwise they are a *NetworkError when song.link
+// can't be reached, an *APIError when it responds with an error status, or ctx.Err() when ctx is canceled.
+func (c *Client) Links(ctx context.Context, query Query) (*Response, error) {
+	response, err := c.do(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	var linksResponse Response
+	if err := json.NewDecoder(response.Body).Decode(&linksResponse); err != nil {
+		if ctx.Err() != nil {
+			return nil, requestError(ctx, err)
+		}
+		return nil, fmt.Errorf("error decoding JSON response: %w", err)
+	}
+	return &linksResponse, nil
+}
+
+// BaseURL returns the API endpoint the client makes requests to
+func (c *Client) BaseURL() string {
+	return c.baseURL
+}
+
+// do requests the links for query and returns the first OK response
+func (c *Client) do(ctx context.Context, query Query) (*http.Response, error) {
+	endpoint := c.buildURL(query)
+	for attempt := 0; ; attempt++ {
+		if c.limiter != nil {
+			if err := c.limiter.Wait(ctx); err != nil {
+				if ctx.Err() != nil {
+					return nil, requestError(ctx, err)
+				}
+				return nil, fmt.Errorf("error waiting for rate limiter: %w", err)
+			}
+		}
+
+		request, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+		if err != nil {
+			return nil, fmt.Errorf("error creating HTTP request: %w", err)
+		}
+		response, err := c.httpClient.Do(request)
+		if err != nil {
+			return nil, requestError(ctx, err)
+		}
+
+		if response.StatusCode == http.StatusOK {
+			return response, nil
+		}
+		response.Body.Close()
+
+		apiErr := &APIError{StatusCode: response.StatusCode, Status: response.Status}
+		if !shouldRetry(response.StatusCode) || attempt >= c.maxRetries {
+			return nil, apiErr
+		}
+		delay := retryDelay(response, attempt)
+		if delay > maxRetryAfter {
+			return nil, fmt.Errorf("%w (retry after %s)", apiErr, delay.Round(time.Second))
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, requestError(ctx, ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+// buildURL returns the links endpoint URL for query
+func (c *Client) buildURL(query Query) string {
+	endpoint, err := url.Parse(c.baseURL)
+	if err != nil {
+		endpoint, _ = url.Parse(DefaultBaseURL)
+	}
+	endpoint.Path = strings.TrimSuffix(endpoint.Path, "/") + "/links"
+	values := endpoint.Query()
+	if query.URL != "" {
+		values.Add("url", query.URL)
+	} else {
+		values.Add("platform", query.Platform)
+		values.Add("type", query.Type)
+		values.Add("id", query.ID)
+	}
+	if query.Country != "" {
+		values.Add("userCountry", query.Country)
+	}
+	if c.apiKey != "" {
+		values.Add("key", c.apiKey)
+	}
+	endpoint.RawQuery = values.Encode()
+	return endpoint.String()
+}
+
+// APIError is returned when song.link responds with a non-OK status
+type APIError struct {
+	StatusCode int
+	Status     string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("received non-OK HTTP response status: %s", e.Status)
+}
+
+// Unsupported reports whether song.link rejected the input as one it can't resolve
+func (e *APIError) Unsupported() bool {
+	return e.StatusCode == http.StatusBadRequest || e.StatusCode == http.StatusNotFound
+}
+
+// ErrTimeout is returned when a request doesn't finish before the context deadline or the HTTP client timeout
+var ErrTimeout = errors.New("request timed out")
+
+// NetworkError is returned when a request fails before a response is received, e.g. because of
+// a DNS lookup or connection failure
+type NetworkError struct {
+	Err error
+}
+
+func (e *NetworkError) Error() string {
+	return fmt.Sprintf("network error: %v", e.Err)
+}
+
+func (e *NetworkError) Unwrap() error {
+	return e.Err
+}
+
+// requestError classifies an error from making a request or reading its response
+// as a cancellation, a timeout or a network error
+func requestError(ctx context.Context, err error) error {
+	if errors.Is(ctx.Err(), context.Canceled) {
+		return ctx.Err()
+	}
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return fmt.Errorf("%w: %w", ErrTimeout, err)
+	}
+	return &NetworkError{Err: err}
+}
+
+// shouldRetry reports whether a response status is worth retrying
+func shouldRetry(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || statusCode >= http.StatusInternalServerError
+}
+
+// retryDelay returns how long to wait before retry number attempt (starting at 0).
+// A Retry-After header is honored; otherwise the delay grows exponentially with jitter.
+func retryDelay(response *http.Response, attempt int) time.Duration {
+	if delay, ok := parseRetryAfter(response.Header.Get("Retry-After")); ok {
+		return delay
+	}
+
+	backoff := baseRetryDelay << attempt
+	if backoff > maxRetryDelay || backoff <= 0 {
+		backoff = maxRetryDelay
+	}
+	// Wait between half and all of the backoff so that concurrent clients spread out
+	return backoff/2 + rand.N(backoff/2+1)
+}
+
+// parseRetryAfter parses a Retry-After header given either in seconds or as an HTTP date
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		delay := time.Until(date)
+		if delay < 0 {
+			delay = 0
+		}
+		return delay, true
+	}
+	return 0, false
+}
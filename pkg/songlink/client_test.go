@@ -0,0 +1,146 @@
+package songlink
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestLinks(t *testing.T) {
+	searchURL := "https://music.apple.com/fi/album/caravan/1572919347?i=1572919354"
+
+	// Mock HTTP server to return a 200 OK response with a sample JSON response body
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/links" || r.URL.Query().Get("url") != searchURL || r.URL.Query().Get("key") != "test-key" {
+			t.Errorf("unexpected request to the mock server: %s", r.URL)
+		}
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintln(w, `{"pageUrl": "https://song.link/fi/i/1572919354", "linksByPlatform": {"spotify": {"url": "https://open.spotify.com/track/2Xtsv7BUMrNodQWH2JPOc0"}}}`)
+	}))
+	defer server.Close()
+
+	client := NewClient(WithBaseURL(server.URL), WithAPIKey("test-key"))
+	linksResponse, err := client.Links(context.Background(), Query{URL: searchURL})
+	if err != nil {
+		t.Fatalf("Links(%q) returned an unexpected error: %v", searchURL, err)
+	}
+
+	if linksResponse.PageURL != "https://song.link/fi/i/1572919354" {
+		t.Errorf("Links(%q) returned an unexpected page URL: %s", searchURL, linksResponse.PageURL)
+	}
+
+	expectedSpotifyURL := "https://open.spotify.com/track/2Xtsv7BUMrNodQWH2JPOc0"
+	if linksResponse.LinksByPlatform["spotify"].URL != expectedSpotifyURL {
+		t.Errorf("Links(%q) returned an unexpected Spotify URL: %s (want %s)", searchURL, linksResponse.LinksByPlatform["spotify"].URL, expectedSpotifyURL)
+	}
+}
+
+func TestLinksRetries(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if requests == 2 {
+			fmt.Fprintln(w, `{"pageUrl": "https://song.link/i/1"}`)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	client := NewClient(WithBaseURL(server.URL))
+	if _, err := client.Links(context.Background(), Query{URL: "https://open.spotify.com/track/1"}); err != nil {
+		t.Fatalf("Links returned an unexpected error after a retry: %v", err)
+	}
+	if requests != 2 {
+		t.Errorf("made %d requests; want 2", requests)
+	}
+
+	// 404s aren't retried and are reported as unsupported
+	_, err := client.Links(context.Background(), Query{URL: "https://open.spotify.com/track/2"})
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || !apiErr.Unsupported() || requests != 3 {
+		t.Errorf("Links error = %v after %d requests; want an unsupported APIError after 3", err, requests)
+	}
+}
+
+func TestLinksErrors(t *testing.T) {
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer slow.Close()
+	defer close(release)
+
+	client := NewClient(WithBaseURL(slow.URL))
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := client.Links(ctx, Query{URL: "https://open.spotify.com/track/1"}); !errors.Is(err, ErrTimeout) {
+		t.Errorf("Links error = %v after the deadline; want ErrTimeout", err)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	if _, err := client.Links(ctx, Query{URL: "https://open.spotify.com/track/1"}); !errors.Is(err, context.Canceled) || errors.Is(err, ErrTimeout) {
+		t.Errorf("Links error = %v after canceling; want context.Canceled", err)
+	}
+
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+	_, err := NewClient(WithBaseURL(closed.URL)).Links(context.Background(), Query{URL: "https://open.spotify.com/track/1"})
+	var networkErr *NetworkError
+	if !errors.As(err, &networkErr) {
+		t.Errorf("Links error = %v for an unreachable server; want a NetworkError", err)
+	}
+}
+
+func TestBuildURL(t *testing.T) {
+	searchURL := "https://music.apple.com/fi/album/caravan/1572919347?i=1572919354"
+	expectedURL := "https://api.song.link/v1-alpha.1/links?url=https%3A%2F%2Fmusic.apple.com%2Ffi%2Falbum%2Fcaravan%2F1572919347%3Fi%3D1572919354"
+	actualURL := NewClient().buildURL(Query{URL: searchURL})
+	if actualURL != expectedURL {
+		t.Errorf("buildURL(%q) = %q; want %q", searchURL, actualURL, expectedURL)
+	}
+
+	expectedURL = "http://localhost:8080/proxy/links?key=secret&url=https%3A%2F%2Fmusic.apple.com%2Ffi%2Falbum%2Fcaravan%2F1572919347%3Fi%3D1572919354&userCountry=GB"
+	actualURL = NewClient(WithBaseURL("http://localhost:8080/proxy/"), WithAPIKey("secret")).buildURL(Query{URL: searchURL, Country: "GB"})
+	if actualURL != expectedURL {
+		t.Errorf("buildURL(%q) with a custom base URL and key = %q; want %q", searchURL, actualURL, expectedURL)
+	}
+}
+
+func TestBuildURLForID(t *testing.T) {
+	query := Query{Platform: "spotify", Type: "song", ID: "2Xtsv7BUMrNodQWH2JPOc0"}
+	expectedURL := "https://api.song.link/v1-alpha.1/links?id=2Xtsv7BUMrNodQWH2JPOc0&platform=spotify&type=song"
+	if actualURL := NewClient().buildURL(query); actualURL != expectedURL {
+		t.Errorf("buildURL(%+v) = %q; want %q", query, actualURL, expectedURL)
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	response := &http.Response{Header: http.Header{}}
+	response.Header.Set("Retry-After", "7")
+	if delay := retryDelay(response, 0); delay != 7*time.Second {
+		t.Errorf("retryDelay with Retry-After: 7 = %s; want 7s", delay)
+	}
+
+	response.Header.Del("Retry-After")
+	for attempt := 0; attempt < 8; attempt++ {
+		backoff := baseRetryDelay << attempt
+		if backoff > maxRetryDelay {
+			backoff = maxRetryDelay
+		}
+		delay := retryDelay(response, attempt)
+		if delay < backoff/2 || delay > backoff {
+			t.Errorf("retryDelay(attempt %d) = %s; want between %s and %s", attempt, delay, backoff/2, backoff)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package songlink
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ErrUnsupportedURL is returned for input that isn't a link to a music service song.link can resolve
+var ErrUnsupportedURL = errors.New("not a supported music URL")
+
+// MusicURL is an input URL after normalization
+type MusicURL struct {
+	URL string
+	// Platform is the Odesli name of the service the URL points to, e.g. "spotify" or "youtubeMusic"
+	Platform string
+}
+
+// musicHosts maps hosts to the platform they belong to. Subdomains match too, and an entry ending in
+// "." matches any top-level domain (amazon.de, music.yandex.ru, ...). More specific entries come first.
+var musicHosts = []struct {
+	host     string
+	platform string
+}{
+	{"open.spotify.com", "spotify"},
+	{"play.spotify.com", "spotify"},
+	{"music.apple.com", "appleMusic"},
+	{"itunes.apple.com", "itunes"},
+	{"music.youtube.com", "youtubeMusic"},
+	{"youtube.com", "youtube"},
+	{"play.google.com", "google"},
+	{"pandora.com", "pandora"},
+	{"deezer.com", "deezer"},
+	{"tidal.com", "tidal"},
+	{"music.amazon.", "amazonMusic"},
+	{"amazon.", "amazonStore"},
+	{"soundcloud.com", "soundcloud"},
+	{"napster.com", "napster"},
+	{"music.yandex.", "yandex"},
+	{"spinrilla.com", "spinrilla"},
+	{"audius.co", "audius"},
+	{"anghami.com", "anghami"},
+	{"boomplay.com", "boomplay"},
+	{"audiomack.com", "audiomack"},
+	{"bandcamp.com", "bandcamp"},
+}
+
+// shortLinkHosts are link shorteners whose redirect is followed to find the real URL
+var shortLinkHosts = []string{
+	"spotify.link",
+	"spotify.app.link",
+	"deezer.page.link",
+	"link.deezer.com",
+	"on.soundcloud.com",
+	"apple.co",
+	"tidal.link",
+	"amzn.to",
+}
+
+// trackingParams are query parameters added by share buttons that don't identify the music
+var trackingParams = map[string]bool{
+	"si":       true,
+	"feature":  true,
+	"context":  true,
+	"nd":       true,
+	"fbclid":   true,
+	"gclid":    true,
+	"igshid":   true,
+	"ls":       true,
+	"app":      true,
+	"pp":       true,
+	"referral": true,
+}
+
+// expandShortLink returns the URL a short link redirects to
+func (c *Client) expandShortLink(ctx context.Context, shortURL string) (string, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, shortURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("error expanding short link: %w", err)
+	}
+	response, err := c.httpClient.Do(request)
+	if err != nil {
+		return "", fmt.Errorf("error expanding short link: %w", requestError(ctx, err))
+	}
+	response.Body.Close()
+	return response.Request.URL.String(), nil
+}
+
+// NormalizeURL prepares an input URL for song.link: short links are expanded, youtu.be links are
+// rewritten to youtube.com and tracking parameters are removed. URLs that don't point to a known
+// music service are rejected with ErrUnsupportedURL so that they don't use up API quota.
+func (c *Client) NormalizeURL(ctx context.Context, rawURL string) (MusicURL, error) {
+	parsed, err := parseWebURL(rawURL)
+	if err != nil {
+		return MusicURL{}, err
+	}
+
+	if isShortLink(parsed.Host) {
+		expanded, err := c.expandShortLink(ctx, parsed.String())
+		if err != nil {
+			return MusicURL{}, err
+		}
+		parsed, err = parseWebURL(expanded)
+		if err != nil {
+			return MusicURL{}, err
+		}
+		if isShortLink(parsed.Host) {
+			return MusicURL{}, fmt.Errorf("%w: short link %s didn't redirect to a music service", ErrUnsupportedURL, rawURL)
+		}
+	}
+
+	// youtu.be/<id> is the same video as youtube.com/watch?v=<id>
+	if HostMatches(parsed.Host, "youtu.be") {
+		id := strings.Trim(parsed.Path, "/")
+		if id == "" {
+			return MusicURL{}, fmt.Errorf("%w: %s has no video ID", ErrUnsupportedURL, rawURL)
+		}
+		parsed = &url.URL{Scheme: "https", Host: "www.youtube.com", Path: "/watch", RawQuery: url.Values{"v": {id}}.Encode()}
+	}
+
+	platform := SourcePlatform(parsed.Host)
+	if platform == "" {
+		return MusicURL{}, fmt.Errorf("%w: %s", ErrUnsupportedURL, parsed.Host)
+	}
+
+	values := parsed.Query()
+	for name := range values {
+		if trackingParams[strings.ToLower(name)] || strings.HasPrefix(strings.ToLower(name), "utm_") {
+			values.Del(name)
+		}
+	}
+	parsed.RawQuery = values.Encode()
+	parsed.Fragment = ""
+	parsed.RawFragment = ""
+
+	return MusicURL{URL: parsed.String(), Platform: platform}, nil
+}
+
+// SourcePlatform returns the Odesli platform name for a host, or an empty string if it isn't a known music service
+func SourcePlatform(host string) string {
+	for _, entry := range musicHosts {
+		if HostMatches(host, entry.host) {
+			return entry.platform
+		}
+	}
+	return ""
+}
+
+// parseWebURL parses rawURL and checks that it is an absolute http(s) URL
+func parseWebURL(rawURL string) (*url.URL, error) {
+	parsed, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return nil, fmt.Errorf("%w: %q is not an http(s) URL", ErrUnsupportedURL, rawURL)
+	}
+	parsed.Scheme = "https"
+	parsed.Host = strings.ToLower(parsed.Host)
+	return parsed, nil
+}
+
+func isShortLink(host string) bool {
+	for _, shortHost := range shortLinkHosts {
+		if HostMatches(host, shortHost) {
+			return true
+		}
+	}
+	return false
+}
+
+// HostMatches reports whether host is domain or one of its subdomains.
+// A domain ending in "." matches it under any top-level domain.
+func HostMatches(host, domain string) bool {
+	host = strings.TrimPrefix(strings.ToLower(host), "www.")
+	if strings.HasSuffix(domain, ".") {
+		index := strings.Index("."+host, "."+domain)
+		if index < 0 {
+			return false
+		}
+		// Allow country domains such as amazon.de, amazon.co.uk and amazon.com.br
+		tld := host[index+len(domain):]
+		labels := strings.Split(tld, ".")
+		return tld != "" && (len(labels) == 1 || (len(labels) == 2 && (labels[0] == "co" || labels[0] == "com")))
+	}
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
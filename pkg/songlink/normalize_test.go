@@ -0,0 +1,64 @@
+package songlink
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// redirectTransport answers requests to short links with a redirect and every other request with 200 OK
+type redirectTransport struct {
+	t       *testing.T
+	targets map[string]string
+}
+
+func (rt redirectTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	response := &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: io.NopCloser(strings.NewReader("")), Request: request}
+	if target, ok := rt.targets[request.URL.String()]; ok {
+		response.StatusCode = http.StatusFound
+		response.Header.Set("Location", target)
+	} else if SourcePlatform(request.URL.Host) == "" {
+		rt.t.Errorf("unexpected request to %s", request.URL)
+	}
+	return response, nil
+}
+
+func TestNormalizeURL(t *testing.T) {
+	client := NewClient(WithHTTPClient(&http.Client{Transport: redirectTransport{t: t, targets: map[string]string{
+		"https://spotify.link/abc123": "https://open.spotify.com/track/2Xtsv7BUMrNodQWH2JPOc0?si=xyz&utm_source=copy-link",
+	}}}))
+
+	tests := []struct {
+		input    string
+		url      string
+		platform string
+	}{
+		{"https://spotify.link/abc123", "https://open.spotify.com/track/2Xtsv7BUMrNodQWH2JPOc0", "spotify"},
+		{"https://youtu.be/dQw4w9WgXcQ?si=abc", "https://www.youtube.com/watch?v=dQw4w9WgXcQ", "youtube"},
+		{"https://music.youtube.com/watch?v=dQw4w9WgXcQ&si=abc&feature=share", "https://music.youtube.com/watch?v=dQw4w9WgXcQ", "youtubeMusic"},
+		{"https://music.apple.com/fi/album/caravan/1572919347?i=1572919354&utm_campaign=x", "https://music.apple.com/fi/album/caravan/1572919347?i=1572919354", "appleMusic"},
+		{"http://www.amazon.co.uk/dp/B0001#reviews", "https://www.amazon.co.uk/dp/B0001", "amazonStore"},
+		{"https://artist.bandcamp.com/track/song", "https://artist.bandcamp.com/track/song", "bandcamp"},
+	}
+	for _, test := range tests {
+		input, err := client.NormalizeURL(context.Background(), test.input)
+		if err != nil {
+			t.Errorf("NormalizeURL(%q) returned an unexpected error: %v", test.input, err)
+			continue
+		}
+		if input.URL != test.url || input.Platform != test.platform {
+			t.Errorf("NormalizeURL(%q) = %q (%s); want %q (%s)", test.input, input.URL, input.Platform, test.url, test.platform)
+		}
+	}
+}
+
+func TestNormalizeURLRejectsNonMusicURLs(t *testing.T) {
+	for _, input := range []string{"https://example.com/track/1", "ftp://open.spotify.com/track/1", "not a url", "https://amazon.example.com/dp/1"} {
+		if _, err := NewClient().NormalizeURL(context.Background(), input); !errors.Is(err, ErrUnsupportedURL) {
+			t.Errorf("NormalizeURL(%q) error = %v; want ErrUnsupportedURL", input, err)
+		}
+	}
+}
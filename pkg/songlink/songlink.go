@@ -0,0 +1,249 @@
+// Package songlink resolves music links with the song.link (Odesli) API.
+//
+// A Client turns a streaming service URL, or the ID of a song or album on a platform,
+// into the links for every other platform song.link knows about:
+//
+//	client := songlink.NewClient(songlink.WithAPIKey(key))
+//	response, err := client.Links(ctx, songlink.Query{URL: "https://open.spotify.com/track/2Xtsv7BUMrNodQWH2JPOc0"})
+//	if err != nil {
+//		return err
+//	}
+//	fmt.Println(response.PageURL, response.LinksByPlatform["tidal"].URL)
+package songlink
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Platforms lists every platform Odesli can return in linksByPlatform, in display order
+var Platforms = []string{
+	"spotify",
+	"appleMusic",
+	"itunes",
+	"youtube",
+	"youtubeMusic",
+	"google",
+	"googleStore",
+	"pandora",
+	"deezer",
+	"tidal",
+	"amazonStore",
+	"amazonMusic",
+	"soundcloud",
+	"napster",
+	"yandex",
+	"spinrilla",
+	"audius",
+	"anghami",
+	"boomplay",
+	"audiomack",
+	"bandcamp",
+}
+
+// platformNames are the display names of the platforms whose name isn't just capitalized
+var platformNames = map[string]string{
+	"appleMusic":   "Apple Music",
+	"itunes":       "iTunes",
+	"youtube":      "YouTube",
+	"youtubeMusic": "YouTube Music",
+	"google":       "Google Play Music",
+	"googleStore":  "Google Play Store",
+	"amazonStore":  "Amazon",
+	"amazonMusic":  "Amazon Music",
+	"soundcloud":   "SoundCloud",
+}
+
+// PlatformName returns the display name of a platform, e.g. "Apple Music" for "appleMusic"
+func PlatformName(platform string) string {
+	if name, ok := platformNames[platform]; ok {
+		return name
+	}
+	if platform == "" {
+		return ""
+	}
+	return strings.ToUpper(platform[:1]) + platform[1:]
+}
+
+// Response is the decoded response of the links endpoint
+type Response struct {
+	EntityUniqueID     string             `json:"entityUniqueId"`
+	UserCountry        string             `json:"userCountry"`
+	PageURL            string             `json:"pageUrl"`
+	EntitiesByUniqueID map[string]*Entity `json:"entitiesByUniqueId"`
+	LinksByPlatform    LinksByPlatform    `json:"linksByPlatform"`
+}
+
+// Entity is the metadata a single API provider has for a song or album
+type Entity struct {
+	ID              string   `json:"id"`
+	Type            string   `json:"type"`
+	Title           string   `json:"title"`
+	ArtistName      string   `json:"artistName"`
+	ThumbnailURL    string   `json:"thumbnailUrl"`
+	ThumbnailWidth  int      `json:"thumbnailWidth"`
+	ThumbnailHeight int      `json:"thumbnailHeight"`
+	APIProvider     string   `json:"apiProvider"`
+	Platforms       []string `json:"platforms"`
+}
+
+// String returns the entity as "Title — Artist"
+func (e *Entity) String() string {
+	if e.ArtistName == "" {
+		return e.Title
+	}
+	return fmt.Sprintf("%s — %s", e.Title, e.ArtistName)
+}
+
+// Entity returns the entity the input resolved to, or nil if the response doesn't include it
+func (r *Response) Entity() *Entity {
+	return r.EntitiesByUniqueID[r.EntityUniqueID]
+}
+
+// AlbumQuery returns the query resolving the album of a song. Apple Music and iTunes song links point into
+// their album (https://music.apple.com/us/album/caravan/1572919347?i=1572919354), which gives its ID.
+// It returns false if the response has no such link.
+func (r *Response) AlbumQuery() (Query, bool) {
+	for _, platform := range []string{"appleMusic", "itunes"} {
+		parsed, err := url.Parse(r.LinksByPlatform[platform].URL)
+		if err != nil || parsed.Query().Get("i") == "" {
+			continue
+		}
+		segments := strings.Split(strings.Trim(parsed.Path, "/"), "/")
+		for _, segment := range segments[:len(segments)-1] {
+			if segment == "album" {
+				return Query{Platform: "appleMusic", Type: "album", ID: segments[len(segments)-1]}, true
+			}
+		}
+	}
+	return Query{}, false
+}
+
+// LinksByPlatform maps an Odesli platform name (e.g. "spotify", "tidal") to its link
+type LinksByPlatform map[string]PlatformMusic
+
+// PlatformMusic is the link to a song or album on one platform
+type PlatformMusic struct {
+	URL                 string `json:"url"`
+	NativeAppURIMobile  string `json:"nativeAppUriMobile,omitempty"`
+	NativeAppURIDesktop string `json:"nativeAppUriDesktop,omitempty"`
+	EntityUniqueID      string `json:"entityUniqueId"`
+}
+
+// Query identifies what to resolve and for which country: either a URL,
+// or the ID of a song or album on a platform
+type Query struct {
+	URL      string
+	Platform string
+	// Type is "song" or "album"
+	Type string
+	ID   string
+	// Country is the ISO 3166-1 alpha-2 code sent as userCountry; empty lets song.link decide
+	Country string
+}
+
+// String returns the URL of the query, or "platform:type:id" for ID queries
+func (q Query) String() string {
+	if q.URL != "" {
+		return q.URL
+	}
+	return q.Platform + ":" + q.Type + ":" + q.ID
+}
+
+// ParseIDQuery validates a platform, type and ID and returns the query resolving them
+func ParseIDQuery(platform, entityType, id string) (Query, error) {
+	if platform == "" {
+		return Query{}, fmt.Errorf("a platform is required to resolve an ID")
+	}
+	canonical, ok := CanonicalPlatform(strings.TrimSpace(platform))
+	if !ok {
+		return Query{}, fmt.Errorf("unknown platform %q (valid platforms: %s)", platform, strings.Join(Platforms, ", "))
+	}
+
+	entityType = strings.ToLower(strings.TrimSpace(entityType))
+	if entityType != "song" && entityType != "album" {
+		return Query{}, fmt.Errorf("invalid type %q (use song or album)", entityType)
+	}
+
+	id = strings.TrimSpace(id)
+	if id == "" {
+		return Query{}, fmt.Errorf("the ID cannot be empty")
+	}
+
+	return Query{Platform: canonical, Type: entityType, ID: id}, nil
+}
+
+// ParsePlatforms parses a comma-separated list of platform names.
+// Names are matched case-insensitively against Platforms and returned in their canonical form.
+func ParsePlatforms(value string) ([]string, error) {
+	var platforms []string
+	seen := make(map[string]bool)
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		platform, ok := CanonicalPlatform(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown platform %q (valid platforms: %s)", name, strings.Join(Platforms, ", "))
+		}
+		if !seen[platform] {
+			seen[platform] = true
+			platforms = append(platforms, platform)
+		}
+	}
+	return platforms, nil
+}
+
+// CanonicalPlatform returns the Odesli spelling of a platform name
+func CanonicalPlatform(name string) (string, bool) {
+	for _, platform := range Platforms {
+		if strings.EqualFold(platform, name) {
+			return platform, true
+		}
+	}
+	return "", false
+}
+
+// ParseCountry validates an ISO 3166-1 alpha-2 country code and returns it in upper case
+func ParseCountry(value string) (string, error) {
+	country := strings.ToUpper(strings.TrimSpace(value))
+	if country == "" {
+		return "", nil
+	}
+	if !isCountryCode(country) {
+		return "", fmt.Errorf("invalid country %q (use a two-letter code such as US, GB or FI)", value)
+	}
+	return country, nil
+}
+
+// isCountryCode reports whether s looks like an ISO 3166-1 alpha-2 code
+func isCountryCode(s string) bool {
+	if len(s) != 2 {
+		return false
+	}
+	for _, r := range s {
+		if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') {
+			return false
+		}
+	}
+	return true
+}
+
+// StripLocale removes the country segment from a song.link page URL,
+// e.g. https://song.link/fi/i/1572919354 becomes https://song.link/i/1572919354
+func StripLocale(pageURL string) string {
+	parsed, err := url.Parse(pageURL)
+	if err != nil {
+		return pageURL
+	}
+
+	segments := strings.Split(strings.TrimPrefix(parsed.Path, "/"), "/")
+	if len(segments) < 2 || !isCountryCode(segments[0]) {
+		return pageURL
+	}
+	parsed.Path = "/" + strings.Join(segments[1:], "/")
+	parsed.RawPath = ""
+	return parsed.String()
+}
@@ -0,0 +1,119 @@
+package songlink
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestParsePlatforms(t *testing.T) {
+	platforms, err := ParsePlatforms("Spotify, tidal,youtubemusic,spotify")
+	if err != nil {
+		t.Fatalf("ParsePlatforms returned an unexpected error: %v", err)
+	}
+	expected := []string{"spotify", "tidal", "youtubeMusic"}
+	if strings.Join(platforms, ",") != strings.Join(expected, ",") {
+		t.Errorf("ParsePlatforms() = %v; want %v", platforms, expected)
+	}
+
+	if _, err := ParsePlatforms("spotify,myspace"); err == nil {
+		t.Error("ParsePlatforms should reject unknown platforms")
+	}
+}
+
+func TestDecodeEntities(t *testing.T) {
+	body := `{"entityUniqueId": "ITUNES_SONG::1572919354", "pageUrl": "https://song.link/i/1572919354",
+		"entitiesByUniqueId": {
+			"ITUNES_SONG::1572919354": {"id": "1572919354", "type": "song", "title": "Caravan", "artistName": "Duke Ellington",
+				"thumbnailUrl": "https://is1-ssl.mzstatic.com/image/thumb/cover.jpg", "thumbnailWidth": 512, "thumbnailHeight": 512,
+				"apiProvider": "itunes", "platforms": ["appleMusic", "itunes"]}
+		}}`
+
+	var linksResponse Response
+	if err := json.Unmarshal([]byte(body), &linksResponse); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	entity := linksResponse.Entity()
+	if entity == nil {
+		t.Fatal("Entity() returned nil; want the source entity")
+	}
+	if entity.String() != "Caravan — Duke Ellington" {
+		t.Errorf("entity.String() = %q; want %q", entity.String(), "Caravan — Duke Ellington")
+	}
+	if entity.ThumbnailWidth != 512 || entity.APIProvider != "itunes" || len(entity.Platforms) != 2 {
+		t.Errorf("entity decoded incorrectly: %+v", entity)
+	}
+}
+
+func TestStripLocale(t *testing.T) {
+	tests := []struct {
+		pageURL  string
+		expected string
+	}{
+		{"https://song.link/fi/i/1572919354", "https://song.link/i/1572919354"},
+		{"https://song.link/us/s/2Xtsv7BUMrNodQWH2JPOc0", "https://song.link/s/2Xtsv7BUMrNodQWH2JPOc0"},
+		{"https://album.link/gb/i/1572919347", "https://album.link/i/1572919347"},
+		// Only a leading two-letter segment is a locale
+		{"https://song.link/i/1572919354", "https://song.link/i/1572919354"},
+		{"https://song.link/s/fi5h", "https://song.link/s/fi5h"},
+		{"https://odesli.co/fiona", "https://odesli.co/fiona"},
+	}
+	for _, tt := range tests {
+		if actual := StripLocale(tt.pageURL); actual != tt.expected {
+			t.Errorf("StripLocale(%q) = %q; want %q", tt.pageURL, actual, tt.expected)
+		}
+	}
+}
+
+func TestParseIDQuery(t *testing.T) {
+	query, err := ParseIDQuery("Spotify", "Song", " 2Xtsv7BUMrNodQWH2JPOc0 ")
+	if err != nil {
+		t.Fatalf("ParseIDQuery returned an unexpected error: %v", err)
+	}
+	expected := Query{Platform: "spotify", Type: "song", ID: "2Xtsv7BUMrNodQWH2JPOc0"}
+	if query != expected {
+		t.Errorf("ParseIDQuery() = %+v; want %+v", query, expected)
+	}
+
+	invalid := [][3]string{
+		{"", "song", "1"},
+		{"myspace", "song", "1"},
+		{"tidal", "playlist", "1"},
+		{"tidal", "album", ""},
+	}
+	for _, args := range invalid {
+		if _, err := ParseIDQuery(args[0], args[1], args[2]); err == nil {
+			t.Errorf("ParseIDQuery(%q, %q, %q) should return an error", args[0], args[1], args[2])
+		}
+	}
+}
+
+func TestAlbumQuery(t *testing.T) {
+	song := &Response{LinksByPlatform: LinksByPlatform{
+		"spotify":    {URL: "https://open.spotify.com/track/2Xtsv7BUMrNodQWH2JPOc0"},
+		"appleMusic": {URL: "https://geo.music.apple.com/us/album/_/1572919347?i=1572919354&mt=1&app=music"},
+	}}
+	query, ok := song.AlbumQuery()
+	if !ok || query != (Query{Platform: "appleMusic", Type: "album", ID: "1572919347"}) {
+		t.Errorf("AlbumQuery() = %+v, %t; want the Apple Music album 1572919347", query, ok)
+	}
+
+	album := &Response{LinksByPlatform: LinksByPlatform{
+		"appleMusic": {URL: "https://geo.music.apple.com/us/album/_/1572919347?mt=1&app=music"},
+	}}
+	if query, ok := album.AlbumQuery(); ok {
+		t.Errorf("AlbumQuery() = %+v for an album link; want false", query)
+	}
+	if query, ok := (&Response{}).AlbumQuery(); ok {
+		t.Errorf("AlbumQuery() = %+v without Apple Music links; want false", query)
+	}
+}
+
+func TestPlatformName(t *testing.T) {
+	for platform, expected := range map[string]string{"appleMusic": "Apple Music", "tidal": "Tidal", "soundcloud": "SoundCloud"} {
+		if name := PlatformName(platform); name != expected {
+			t.Errorf("PlatformName(%q) = %q; want %q", platform, name, expected)
+		}
+	}
+}
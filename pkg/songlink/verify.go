@@ -0,0 +1,172 @@
+package songlink
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Match is how well the entity song.link found on a platform matches the entity the input resolved to
+type Match struct {
+	Platform string
+	Entity   *Entity
+	// Confidence is between 0 (a different song) and 1 (the same title, artist and version)
+	Confidence float64
+	// Reasons describe what differs, e.g. "title differs" or "live version"
+	Reasons []string
+}
+
+// versionMarkers are the words in a title that set a recording apart from the original.
+// A marker on only one side of a match usually means song.link picked a different recording.
+var versionMarkers = []string{
+	"live", "remaster", "cover", "acoustic", "remix", "instrumental",
+	"karaoke", "demo", "unplugged", "tribute", "reprise", "edit", "mix",
+}
+
+// markerSpellings maps other spellings of version markers to the one in versionMarkers,
+// so that "- 2011 Remaster" and "(Remastered)" are the same version
+var markerSpellings = map[string]string{
+	"remastered": "remaster",
+	"remixed":    "remix",
+	"edited":     "edit",
+	"covered":    "cover",
+}
+
+// Verify compares the title and artist of the entity behind every platform link with the source entity.
+// Matches are returned in the order of Platforms. Links without entity data, or to the source entity itself,
+// are left out, as is everything if the response doesn't include the source entity.
+//
+// Durations aren't compared: song.link doesn't report them, and the Apple Music catalog only has the length of the
+// Apple Music recording, so there is nothing to compare it with. Recordings of different lengths with the same title
+// and version aren't detected.
+func (r *Response) Verify() []Match {
+	source := r.Entity()
+	if source == nil {
+		return nil
+	}
+
+	var matches []Match
+	for _, platform := range Platforms {
+		link, ok := r.LinksByPlatform[platform]
+		if !ok || link.EntityUniqueID == "" || link.EntityUniqueID == r.EntityUniqueID {
+			continue
+		}
+		entity := r.EntitiesByUniqueID[link.EntityUniqueID]
+		if entity == nil {
+			continue
+		}
+		match := compareEntities(source, entity)
+		match.Platform = platform
+		matches = append(matches, match)
+	}
+	return matches
+}
+
+// compareEntities scores how well entity matches source
+func compareEntities(source, entity *Entity) Match {
+	match := Match{Entity: entity}
+	if source.Type != "" && entity.Type != "" && source.Type != entity.Type {
+		match.Reasons = []string{"is " + article(entity.Type) + " " + entity.Type}
+		return match
+	}
+
+	sourceTitle, sourceMarkers := splitTitle(source.Title)
+	title, markers := splitTitle(entity.Title)
+	titleScore := dice(sourceTitle, title)
+	artistScore := overlap(words(source.ArtistName), words(entity.ArtistName))
+	if titleScore < 1 {
+		match.Reasons = append(match.Reasons, "title differs")
+	}
+	if artistScore < 1 {
+		match.Reasons = append(match.Reasons, "artist differs")
+	}
+	match.Confidence = 0.6*titleScore + 0.4*artistScore
+
+	for _, marker := range versionMarkers {
+		switch {
+		case markers[marker] && !sourceMarkers[marker]:
+			match.Reasons = append(match.Reasons, marker+" version")
+			match.Confidence *= 0.7
+		case sourceMarkers[marker] && !markers[marker]:
+			match.Reasons = append(match.Reasons, "not the "+marker+" version")
+			match.Confidence *= 0.7
+		}
+	}
+	return match
+}
+
+// splitTitle returns the words of a title without its version details, such as "(Live)" or
+// "- 2011 Remaster", and the version markers found anywhere in it
+func splitTitle(title string) ([]string, map[string]bool) {
+	markers := make(map[string]bool)
+	for _, word := range words(title) {
+		if marker, ok := markerSpellings[word]; ok {
+			word = marker
+		}
+		for _, marker := range versionMarkers {
+			if word == marker {
+				markers[marker] = true
+			}
+		}
+	}
+
+	base := title
+	if i := strings.IndexAny(base, "([{"); i > 0 {
+		base = base[:i]
+	}
+	if i := strings.Index(base, " - "); i > 0 {
+		base = base[:i]
+	}
+	return words(base), markers
+}
+
+// words splits s into lowercase words, ignoring punctuation and spelling "&" as "and"
+func words(s string) []string {
+	s = strings.ReplaceAll(strings.ToLower(s), "&", " and ")
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// dice returns the Sørensen–Dice coefficient of the word sets a and b
+func dice(a, b []string) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 1
+	}
+	return 2 * float64(common(a, b)) / float64(len(set(a))+len(set(b)))
+}
+
+// overlap returns the share of the smaller word set that is also in the other, so that
+// "Duke Ellington" fully matches "Duke Ellington & His Orchestra"
+func overlap(a, b []string) float64 {
+	smaller := min(len(set(a)), len(set(b)))
+	if smaller == 0 {
+		return 1
+	}
+	return float64(common(a, b)) / float64(smaller)
+}
+
+func common(a, b []string) int {
+	inB := set(b)
+	n := 0
+	for word := range set(a) {
+		if inB[word] {
+			n++
+		}
+	}
+	return n
+}
+
+func set(words []string) map[string]bool {
+	s := make(map[string]bool, len(words))
+	for _, word := range words {
+		s[word] = true
+	}
+	return s
+}
+
+func article(word string) string {
+	if strings.ContainsRune("aeiou", rune(word[0])) {
+		return "an"
+	}
+	return "a"
+}
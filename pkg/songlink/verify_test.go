@@ -0,0 +1,64 @@
+package songlink
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestVerify(t *testing.T) {
+	entity := func(title, artist string) *Entity {
+		return &Entity{Type: "song", Title: title, ArtistName: artist}
+	}
+	response := &Response{
+		EntityUniqueID: "ITUNES_SONG::1",
+		EntitiesByUniqueID: map[string]*Entity{
+			"ITUNES_SONG::1":   entity("Caravan", "Duke Ellington"),
+			"SPOTIFY_SONG::2":  entity("Caravan - Remastered 1999", "Duke Ellington & His Orchestra"),
+			"TIDAL_SONG::3":    entity("Caravan (Live at Newport)", "Duke Ellington"),
+			"DEEZER_SONG::4":   entity("Take the A Train", "Billy Strayhorn"),
+			"YOUTUBE_VIDEO::5": {Type: "album", Title: "Caravan", ArtistName: "Duke Ellington"},
+		},
+		LinksByPlatform: LinksByPlatform{
+			"appleMusic": {EntityUniqueID: "ITUNES_SONG::1"},
+			"spotify":    {EntityUniqueID: "SPOTIFY_SONG::2"},
+			"tidal":      {EntityUniqueID: "TIDAL_SONG::3"},
+			"deezer":     {EntityUniqueID: "DEEZER_SONG::4"},
+			"youtube":    {EntityUniqueID: "YOUTUBE_VIDEO::5"},
+			"pandora":    {EntityUniqueID: "PANDORA_SONG::6"},
+		},
+	}
+
+	expected := []struct {
+		platform   string
+		confidence string
+		reasons    string
+	}{
+		{"spotify", "0.70", "[remaster version]"},
+		{"youtube", "0.00", "[is an album]"},
+		{"deezer", "0.00", "[title differs artist differs]"},
+		{"tidal", "0.70", "[live version]"},
+	}
+	matches := response.Verify()
+	if len(matches) != len(expected) {
+		t.Fatalf("Verify() returned %d matches; want %d: %+v", len(matches), len(expected), matches)
+	}
+	for i, match := range matches {
+		if match.Platform != expected[i].platform ||
+			fmt.Sprintf("%.2f", match.Confidence) != expected[i].confidence ||
+			fmt.Sprint(match.Reasons) != expected[i].reasons {
+			t.Errorf("match %d = %s %.2f %v; want %+v", i, match.Platform, match.Confidence, match.Reasons, expected[i])
+		}
+	}
+
+	if matches := (&Response{}).Verify(); matches != nil {
+		t.Errorf("Verify() without a source entity = %+v; want nil", matches)
+	}
+}
+
+func TestVerifyMarkerSpellings(t *testing.T) {
+	source := &Entity{Type: "song", Title: "Caravan - 2011 Remaster", ArtistName: "Duke Ellington"}
+	entity := &Entity{Type: "song", Title: "Caravan (Remastered)", ArtistName: "Duke Ellington"}
+	if match := compareEntities(source, entity); match.Confidence != 1 || len(match.Reasons) != 0 {
+		t.Errorf("compareEntities() = %.2f %v; want a full match", match.Confidence, match.Reasons)
+	}
+}
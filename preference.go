@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/marcusziade/songlink-cli.git/pkg/songlink"
+)
+
+// defaultPlatformPreference is the order platforms are preferred in unless -prefer or platform_preference is set
+var defaultPlatformPreference = []string{"spotify", "appleMusic", "youtubeMusic", "youtube"}
+
+// configuredPreference returns the platform preference chain from the -prefer flag, the config or the default
+func configuredPreference() ([]string, error) {
+	if *preferFlag != "" {
+		return songlink.ParsePlatforms(*preferFlag)
+	}
+
+	config, err := LoadConfig()
+	if err != nil {
+		return nil, fmt.Errorf("error loading config: %w", err)
+	}
+	if len(config.PlatformPreference) > 0 {
+		return songlink.ParsePlatforms(strings.Join(config.PlatformPreference, ","))
+	}
+	return defaultPlatformPreference, nil
+}
+
+// preferredLink returns the link of the first platform in preference that response has a link for
+func preferredLink(response *songlink.Response, preference []string) (PlatformLink, bool) {
+	for _, platform := range preference {
+		if link, ok := response.LinksByPlatform[platform]; ok && link.URL != "" {
+			return PlatformLink{Platform: platform, URL: link.URL}, true
+		}
+	}
+	return PlatformLink{}, false
+}
+
+// linkSelection is the platforms whose links are included in text output
+type linkSelection struct {
+	Platforms []string
+	// Missing lists the selected platforms without a link or, when falling back on the preference
+	// chain, the preferred platforms that were skipped
+	Missing []string
+	// explicit is set when the platforms were selected with -platforms or the config
+	// rather than taken from the preference chain
+	explicit bool
+}
+
+// selectLinks chooses the platforms for text output. Platforms selected with -platforms or the config are
+// kept as they are; otherwise the first platform in preference that response has a link for is used.
+func selectLinks(response *songlink.Response, platforms, preference []string) linkSelection {
+	if platforms != nil {
+		selection := linkSelection{Platforms: platforms, explicit: true}
+		for _, platform := range platforms {
+			if response.LinksByPlatform[platform].URL == "" {
+				selection.Missing = append(selection.Missing, platform)
+			}
+		}
+		return selection
+	}
+
+	preferred, ok := preferredLink(response, preference)
+	if !ok {
+		return linkSelection{Platforms: []string{}, Missing: preference}
+	}
+	selection := linkSelection{Platforms: []string{preferred.Platform}}
+	for _, platform := range preference {
+		if platform == preferred.Platform {
+			break
+		}
+		selection.Missing = append(selection.Missing, platform)
+	}
+	return selection
+}
+
+// Complete reports whether the output has every link it asked for: all of the selected platforms,
+// or one from the preference chain
+func (s linkSelection) Complete() bool {
+	if s.explicit {
+		return len(s.Missing) == 0
+	}
+	return len(s.Platforms) > 0
+}
+
+// Warning describes the missing links, or returns an empty string when there are none
+func (s linkSelection) Warning() string {
+	switch {
+	case len(s.Missing) == 0:
+		return ""
+	case !s.explicit && len(s.Platforms) > 0:
+		return fmt.Sprintf("no %s link; using %s instead", strings.Join(s.Missing, " or "), s.Platforms[0])
+	default:
+		return fmt.Sprintf("no link for %s", strings.Join(s.Missing, ", "))
+	}
+}
+
+// linksShown reports whether text output made with templateText shows the selected links: when platforms
+// were selected explicitly or the template uses .Links or .Preferred. Only then is a missing link worth a warning.
+func linksShown(platforms []string, templateText string) bool {
+	return platforms != nil || templateLinkFields.MatchString(templateText)
+}
+
+// checkLinks reports the links missing from selection for input: with -strict an incomplete selection
+// is an error, otherwise the missing links are written to stderr as a warning
+func checkLinks(input string, selection linkSelection) error {
+	warning := selection.Warning()
+	if warning == "" {
+		return nil
+	}
+	if *strictFlag && !selection.Complete() {
+		return fmt.Errorf("%s: %s", input, warning)
+	}
+	fmt.Fprintf(os.Stderr, "Warning: %s: %s\n", input, warning)
+	return nil
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/marcusziade/songlink-cli.git/pkg/songlink"
+)
+
+func TestSelectLinks(t *testing.T) {
+	response := &songlink.Response{
+		LinksByPlatform: songlink.LinksByPlatform{
+			"appleMusic": {URL: "https://music.apple.com/us/album/caravan/1572919348?i=1572919354"},
+			"youtube":    {URL: "https://www.youtube.com/watch?v=1bGEWy3Bw7o"},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		platforms []string
+		platform  string
+		warning   string
+		complete  bool
+	}{
+		{"falls back on the preference chain", nil, "[appleMusic]", "no spotify link; using appleMusic instead", true},
+		{"explicit platforms are kept", []string{"youtube", "tidal"}, "[youtube tidal]", "no link for tidal", false},
+		{"explicit platforms with every link", []string{"appleMusic"}, "[appleMusic]", "", true},
+	}
+	for _, tt := range tests {
+		selection := selectLinks(response, tt.platforms, defaultPlatformPreference)
+		if fmt.Sprint(selection.Platforms) != tt.platform {
+			t.Errorf("%s: Platforms = %v; want %s", tt.name, selection.Platforms, tt.platform)
+		}
+		if selection.Warning() != tt.warning {
+			t.Errorf("%s: Warning() = %q; want %q", tt.name, selection.Warning(), tt.warning)
+		}
+		if selection.Complete() != tt.complete {
+			t.Errorf("%s: Complete() = %t; want %t", tt.name, selection.Complete(), tt.complete)
+		}
+	}
+
+	selection := selectLinks(response, nil, []string{"spotify", "tidal"})
+	if len(selection.Platforms) != 0 || selection.Complete() {
+		t.Errorf("selection without any preferred link = %+v; want no platforms and an incomplete selection", selection)
+	}
+	if selection.Warning() != "no link for spotify, tidal" {
+		t.Errorf("Warning() = %q; want %q", selection.Warning(), "no link for spotify, tidal")
+	}
+}
+
+func TestPreferredTemplateField(t *testing.T) {
+	response := &songlink.Response{
+		LinksByPlatform: songlink.LinksByPlatform{
+			"youtubeMusic": {URL: "https://music.youtube.com/watch?v=1bGEWy3Bw7o"},
+		},
+	}
+	data := NewTemplateData(testLinkResult(), response, defaultPlatformPreference)
+
+	output, err := renderTemplate("custom", "{{.Preferred.Platform}} {{.Preferred.URL}}", data)
+	if err != nil {
+		t.Fatalf("renderTemplate returned an unexpected error: %v", err)
+	}
+	if output != "youtubeMusic https://music.youtube.com/watch?v=1bGEWy3Bw7o" {
+		t.Errorf("output = %q; want the youtubeMusic link", output)
+	}
+}
+
+func TestLinksShown(t *testing.T) {
+	tests := []struct {
+		name      string
+		platforms []string
+		template  string
+		expected  bool
+	}{
+		{"page URL only", nil, builtinTemplates["songlink"], false},
+		{"links template", nil, builtinTemplates["links"], true},
+		{"preferred link", nil, "{{.Preferred.URL}}", true},
+		{"single platform by name", nil, `{{.Link "spotify"}}`, false},
+		{"explicit platforms", []string{"spotify"}, builtinTemplates["songlink"], true},
+	}
+	for _, tt := range tests {
+		if shown := linksShown(tt.platforms, tt.template); shown != tt.expected {
+			t.Errorf("%s: linksShown = %t; want %t", tt.name, shown, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+// qrPNGSize is the width and height in pixels of the PNG written by -qr-out
+const qrPNGSize = 512
+
+// writeQRCodes writes the QR codes selected with -qr and -qr-out for the song.link page URLs.
+// -qr-out saves the first page only.
+func writeQRCodes(w io.Writer, pageURLs []string) error {
+	if *qrFlag {
+		for _, pageURL := range pageURLs {
+			// Label the codes when -album-also shows more than one
+			fmt.Fprintln(w)
+			if len(pageURLs) > 1 {
+				fmt.Fprintln(w, pageURL)
+			}
+			if err := writeQR(w, pageURL); err != nil {
+				return err
+			}
+		}
+	}
+	if *qrOutFlag != "" && len(pageURLs) > 0 {
+		if err := writeQRPNG(*qrOutFlag, pageURLs[0]); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Saved the QR code for %s to %s\n", pageURLs[0], *qrOutFlag)
+	}
+	return nil
+}
+
+// writeQR writes content as a QR code made of Unicode half blocks, two modules per character.
+// Light modules are drawn as blocks so that the code scans on dark terminal backgrounds.
+func writeQR(w io.Writer, content string) error {
+	code, err := qrcode.New(content, qrcode.Medium)
+	if err != nil {
+		return fmt.Errorf("error creating QR code: %w", err)
+	}
+	bitmap := code.Bitmap()
+
+	var b strings.Builder
+	for y := 0; y < len(bitmap); y += 2 {
+		for x := range bitmap[y] {
+			top := !bitmap[y][x]
+			// An odd last row is paired with the light quiet zone
+			bottom := y+1 == len(bitmap) || !bitmap[y+1][x]
+			switch {
+			case top && bottom:
+				b.WriteString("█")
+			case top:
+				b.WriteString("▀")
+			case bottom:
+				b.WriteString("▄")
+			default:
+				b.WriteString(" ")
+			}
+		}
+		b.WriteString("\n")
+	}
+	_, err = io.WriteString(w, b.String())
+	return err
+}
+
+// writeQRPNG writes content as a QR code to a PNG file at path
+func writeQRPNG(path, content string) error {
+	if err := qrcode.WriteFile(content, qrcode.Medium, qrPNGSize, path); err != nil {
+		return fmt.Errorf("error writing QR code to %s: %w", path, err)
+	}
+	return nil
+}
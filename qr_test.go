@@ -0,0 +1,48 @@
+package main
+
+import (
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestWriteQR(t *testing.T) {
+	var b strings.Builder
+	if err := writeQR(&b, "https://song.link/i/1572919354"); err != nil {
+		t.Fatalf("writeQR returned an unexpected error: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n")
+	width := utf8.RuneCountInString(lines[0])
+	// Each line holds two rows of modules, and the code is square
+	if rows := len(lines); rows != (width+1)/2 {
+		t.Errorf("QR code has %d lines for %d columns; want %d", rows, width, (width+1)/2)
+	}
+	// The quiet zone around the code is light
+	if strings.Trim(lines[0], "█") != "" {
+		t.Errorf("first line = %q; want the light quiet zone", lines[0])
+	}
+}
+
+func TestWriteQRPNG(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "qr.png")
+	if err := writeQRPNG(path, "https://song.link/i/1572919354"); err != nil {
+		t.Fatalf("writeQRPNG returned an unexpected error: %v", err)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("failed to open the PNG: %v", err)
+	}
+	defer file.Close()
+	img, err := png.Decode(file)
+	if err != nil {
+		t.Fatalf("failed to decode the PNG: %v", err)
+	}
+	if bounds := img.Bounds(); bounds.Dx() != qrPNGSize || bounds.Dy() != qrPNGSize {
+		t.Errorf("PNG is %dx%d; want %dx%d", bounds.Dx(), bounds.Dy(), qrPNGSize, qrPNGSize)
+	}
+}
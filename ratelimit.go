@@ -0,0 +1,215 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// defaultRateLimit is Odesli's quota for requests without an API key, per minute
+const defaultRateLimit = 10
+
+// staleLockAge is how old a lock file must be before it is assumed to be left behind by a crashed process
+const staleLockAge = 10 * time.Second
+
+// RateLimiter is a token bucket whose state is persisted to disk so that
+// separate invocations of the CLI share the same song.link request budget
+type RateLimiter struct {
+	path     string
+	capacity float64
+	// refill is the number of tokens added per second
+	refill float64
+	mu     sync.Mutex
+}
+
+// rateLimitState is the on-disk state of a RateLimiter
+type rateLimitState struct {
+	Tokens  float64   `json:"tokens"`
+	Updated time.Time `json:"updated"`
+}
+
+// NewRateLimiter creates a limiter allowing requestsPerMinute requests, with bursts of up to the same number,
+// whose state is stored at path
+func NewRateLimiter(path string, requestsPerMinute int) *RateLimiter {
+	return &RateLimiter{
+		path:     path,
+		capacity: float64(requestsPerMinute),
+		refill:   float64(requestsPerMinute) / 60,
+	}
+}
+
+var (
+	songlinkLimiter     *RateLimiter
+	songlinkLimiterErr  error
+	songlinkLimiterOnce sync.Once
+)
+
+// getSonglinkLimiter returns the limiter shared by all song.link requests, or nil if rate limiting is disabled.
+// Requests made with an API key aren't limited unless rate_limit is set explicitly.
+func getSonglinkLimiter(api APISettings) (*RateLimiter, error) {
+	songlinkLimiterOnce.Do(func() {
+		config, err := LoadConfig()
+		if err != nil {
+			songlinkLimiterErr = fmt.Errorf("error loading config: %w", err)
+			return
+		}
+		limit := config.RateLimit
+		if limit < 0 {
+			return
+		}
+		if limit == 0 {
+			if api.Key != "" {
+				return
+			}
+			limit = defaultRateLimit
+		}
+
+		configDir, err := GetConfigDir()
+		if err != nil {
+			songlinkLimiterErr = err
+			return
+		}
+		songlinkLimiter = NewRateLimiter(filepath.Join(configDir, "ratelimit.json"), limit)
+	})
+	return songlinkLimiter, songlinkLimiterErr
+}
+
+// Wait blocks until a request may be made or ctx is done
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	for {
+		wait, err := l.take()
+		if err != nil {
+			return err
+		}
+		if wait == 0 {
+			return nil
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// take consumes a token if one is available. Otherwise it returns how long to wait for the next one.
+func (l *RateLimiter) take() (time.Duration, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	unlock, err := lockFile(l.path + ".lock")
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	state := l.load()
+	now := time.Now()
+	if elapsed := now.Sub(state.Updated).Seconds(); elapsed > 0 {
+		state.Tokens = math.Min(l.capacity, state.Tokens+elapsed*l.refill)
+	}
+	state.Updated = now
+
+	var wait time.Duration
+	if state.Tokens >= 1 {
+		state.Tokens--
+	} else {
+		wait = time.Duration((1 - state.Tokens) / l.refill * float64(time.Second))
+	}
+
+	if err := l.save(state); err != nil {
+		return 0, err
+	}
+	return wait, nil
+}
+
+// load reads the limiter state. A missing or unreadable state file starts with a full bucket.
+func (l *RateLimiter) load() rateLimitState {
+	full := rateLimitState{Tokens: l.capacity, Updated: time.Now()}
+
+	data, err := os.ReadFile(l.path)
+	if err != nil {
+		return full
+	}
+	var state rateLimitState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return full
+	}
+	return state
+}
+
+// save writes the limiter state, replacing the previous file atomically
+func (l *RateLimiter) save(state rateLimitState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to marshal rate limit state: %w", err)
+	}
+
+	tmpPath := l.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write rate limit state: %w", err)
+	}
+	if err := os.Rename(tmpPath, l.path); err != nil {
+		return fmt.Errorf("failed to write rate limit state: %w", err)
+	}
+	return nil
+}
+
+// lockFile takes an exclusive lock by creating path, waiting while another process holds it.
+// It returns a function that releases the lock.
+func lockFile(path string) (func(), error) {
+	deadline := time.Now().Add(2 * staleLockAge)
+	for {
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err == nil {
+			file.Close()
+			return func() { os.Remove(path) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("failed to create lock file: %w", err)
+		}
+
+		// Remove locks left behind by processes that exited without releasing them
+		if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) > staleLockAge {
+			if removeStaleLock(path, info) {
+				continue
+			}
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timed out waiting for lock file %s", path)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// removeStaleLock removes the lock file at path if it is still the stale one described by stale. Breaking a lock
+// is itself locked, so that of two processes that both found the lock stale, the second can't remove the lock the
+// first takes after breaking it. It reports whether the lock was removed.
+func removeStaleLock(path string, stale os.FileInfo) bool {
+	breakPath := path + ".break"
+	file, err := os.OpenFile(breakPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		// A process that crashed while breaking the lock would block everyone else
+		if info, err := os.Stat(breakPath); err == nil && time.Since(info.ModTime()) > staleLockAge {
+			os.Remove(breakPath)
+		}
+		return false
+	}
+	file.Close()
+	defer os.Remove(breakPath)
+
+	info, err := os.Stat(path)
+	if err != nil || !os.SameFile(info, stale) || !info.ModTime().Equal(stale.ModTime()) {
+		return false
+	}
+	return os.Remove(path) == nil
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterSharesStateOnDisk(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ratelimit.json")
+
+	// Two limiters stand in for two invocations of the CLI
+	first := NewRateLimiter(path, 2)
+	second := NewRateLimiter(path, 2)
+
+	for i, limiter := range []*RateLimiter{first, second} {
+		wait, err := limiter.take()
+		if err != nil {
+			t.Fatalf("take() returned an unexpected error: %v", err)
+		}
+		if wait != 0 {
+			t.Errorf("request %d had to wait %s; want an immediate token", i+1, wait)
+		}
+	}
+
+	wait, err := first.take()
+	if err != nil {
+		t.Fatalf("take() returned an unexpected error: %v", err)
+	}
+	if wait <= 0 || wait > 30*time.Second {
+		t.Errorf("third request waits %s; want up to 30s for the bucket to refill", wait)
+	}
+}
+
+func TestLockFileBreaksStaleLocks(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ratelimit.json.lock")
+	old := time.Now().Add(-2 * staleLockAge)
+	if err := os.WriteFile(path, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, old, old); err != nil {
+		t.Fatal(err)
+	}
+	stale, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Another process broke the stale lock and took a fresh one after this one found it stale
+	os.Remove(path)
+	unlock, err := lockFile(path)
+	if err != nil {
+		t.Fatalf("lockFile returned an unexpected error: %v", err)
+	}
+	if removeStaleLock(path, stale) {
+		t.Error("removeStaleLock removed a fresh lock that replaced the stale one")
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("the fresh lock is gone: %v", err)
+	}
+	unlock()
+
+	if err := os.WriteFile(path, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, old, old); err != nil {
+		t.Fatal(err)
+	}
+	unlock, err = lockFile(path)
+	if err != nil {
+		t.Fatalf("lockFile didn't break a stale lock: %v", err)
+	}
+	unlock()
+}
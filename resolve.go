@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/marcusziade/songlink-cli.git/pkg/search"
+	"github.com/marcusziade/songlink-cli.git/pkg/songlink"
+)
+
+// executeResolve handles the resolve subcommand
+func executeResolve(ctx context.Context, args []string) error {
+	resolveCmd := flag.NewFlagSet("resolve", flag.ExitOnError)
+	platformFlag := resolveCmd.String("platform", "", "Platform the ID belongs to, e.g. spotify, appleMusic or tidal")
+	typeFlag := resolveCmd.String("type", "song", "Type of the ID: song or album")
+	idFlag := resolveCmd.String("id", "", "ID of the song or album on -platform")
+	isrcFlag := resolveCmd.String("isrc", "", "Look up a song by ISRC in the Apple Music catalog")
+	upcFlag := resolveCmd.String("upc", "", "Look up an album by UPC in the Apple Music catalog")
+
+	if err := resolveCmd.Parse(args); err != nil {
+		return err
+	}
+	if resolveCmd.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments: %s (to resolve a URL, pass it without the resolve subcommand)", strings.Join(resolveCmd.Args(), " "))
+	}
+
+	selected := 0
+	for _, value := range []string{*idFlag, *isrcFlag, *upcFlag} {
+		if value != "" {
+			selected++
+		}
+	}
+	if selected != 1 {
+		return errors.New("give exactly one of -id (with -platform), -isrc or -upc")
+	}
+
+	client, err := newSonglinkClient()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := linkContext(ctx)
+	defer cancel()
+
+	switch {
+	case *isrcFlag != "":
+		query, err := lookupCatalogID(ctx, *isrcFlag, search.Song)
+		if err != nil {
+			return err
+		}
+		return outputLinks(ctx, client, "isrc:"+*isrcFlag, query)
+	case *upcFlag != "":
+		query, err := lookupCatalogID(ctx, *upcFlag, search.Album)
+		if err != nil {
+			return err
+		}
+		return outputLinks(ctx, client, "upc:"+*upcFlag, query)
+	default:
+		if *platformFlag == "" {
+			return errors.New("-platform is required with -id")
+		}
+		query, err := songlink.ParseIDQuery(*platformFlag, *typeFlag, *idFlag)
+		if err != nil {
+			return err
+		}
+		return outputLinks(ctx, client, query.String(), query)
+	}
+}
+
+// lookupCatalogID finds an ISRC (for songs) or UPC (for albums) in the Apple Music catalog
+// and returns the query resolving the Apple Music ID it belongs to
+func lookupCatalogID(ctx context.Context, code string, searchType search.Type) (songlink.Query, error) {
+	config, err := LoadConfig()
+	if err != nil {
+		return songlink.Query{}, fmt.Errorf("error loading config: %w", err)
+	}
+	if !config.HasAppleMusicCredentials() {
+		return songlink.Query{}, errors.New("ISRC and UPC lookups use the Apple Music catalog; run 'songlink-cli config' to set up credentials")
+	}
+	searcher, err := newSearchClient(config)
+	if err != nil {
+		return songlink.Query{}, fmt.Errorf("error creating music searcher: %w", err)
+	}
+
+	storefront, err := configuredStorefront()
+	if err != nil {
+		return songlink.Query{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	var result *search.Result
+	if searchType == search.Album {
+		result, err = searcher.LookupUPC(ctx, strings.TrimSpace(code), storefront)
+	} else {
+		result, err = searcher.LookupISRC(ctx, strings.TrimSpace(code), storefront)
+	}
+	if err != nil {
+		return songlink.Query{}, err
+	}
+
+	return songlink.Query{Platform: "appleMusic", Type: string(result.Type), ID: result.ID}, nil
+}
+
+// configuredStorefront returns the Apple Music storefront of the configured country, which defaults to the US
+func configuredStorefront() (string, error) {
+	country, err := configuredCountry()
+	if err != nil {
+		return "", err
+	}
+	if country == "" {
+		return "us", nil
+	}
+	return strings.ToLower(country), nil
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/atotto/clipboard"
+)
+
+// urlPattern matches http(s) URLs in free text. Trailing punctuation is trimmed separately.
+var urlPattern = regexp.MustCompile(`https?://[^\s<>"'()\[\]{}]+`)
+
+// executeRewrite handles the rewrite subcommand
+func executeRewrite(ctx context.Context, args []string) error {
+	rewriteCmd := flag.NewFlagSet("rewrite", flag.ExitOnError)
+	workersFlag := rewriteCmd.Int("workers", 4, "Number of links to resolve concurrently")
+
+	if err := rewriteCmd.Parse(args); err != nil {
+		return err
+	}
+	if *workersFlag < 1 {
+		return fmt.Errorf("-workers must be at least 1")
+	}
+
+	text, err := readRewriteInput(rewriteCmd.Args())
+	if err != nil {
+		return err
+	}
+	urls := findURLs(text)
+	if len(urls) == 0 {
+		return errors.New("no links found in the input")
+	}
+
+	client, err := newSonglinkClient()
+	if err != nil {
+		return err
+	}
+	country, err := configuredCountry()
+	if err != nil {
+		return err
+	}
+	templateName, templateText, err := rewriteTemplate()
+	if err != nil {
+		return err
+	}
+	platforms, err := configuredPlatforms()
+	if err != nil {
+		return err
+	}
+	preference, err := configuredPreference()
+	if err != nil {
+		return err
+	}
+	keepLocale, err := keepLocale()
+	if err != nil {
+		return err
+	}
+
+	entries := resolveBatch(ctx, client, country, urls, *workersFlag)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	replacements := make(map[string]string)
+	var recorded []HistoryEntry
+	found, replaced, incomplete := 0, 0, 0
+	check := linksShown(platforms, templateText)
+	for _, entry := range entries {
+		// Links to other sites are left as they are
+		if entry.unsupported {
+			continue
+		}
+		found++
+		if entry.err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", entry.inputURL, entry.err)
+			continue
+		}
+		// With -strict, links missing platform links are left as they are
+		selection := selectLinks(entry.response, platforms, preference)
+		if check {
+			if err := checkLinks(entry.inputURL, selection); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				incomplete++
+				continue
+			}
+		}
+		data := NewTemplateData(NewLinkResult(entry.inputURL, entry.response, selection.Platforms, keepLocale), entry.response, preference)
+		output, err := renderTemplate(templateName, templateText, data)
+		if err != nil {
+			return err
+		}
+		recorded = append(recorded, newHistoryEntry(entry.inputURL, entry.response, keepLocale, templateName, output))
+		replacements[entry.inputURL] = output
+		replaced++
+	}
+	recordHistory(recorded...)
+
+	rewritten := rewriteText(text, replacements)
+	fmt.Print(rewritten)
+	if !strings.HasSuffix(rewritten, "\n") {
+		fmt.Println()
+	}
+	fmt.Fprintf(os.Stderr, "Replaced %d of %d music links\n", replaced, found)
+
+	// The text is still output with the links -strict rejected left as they are, but the exit code says so
+	if !*noCopyFlag {
+		if err := clipboard.WriteAll(rewritten); err != nil {
+			return fmt.Errorf("error copying output string to clipboard: %w", err)
+		}
+		fmt.Fprintln(os.Stderr, "Copied to the clipboard")
+	}
+	return incompleteBatchError(incomplete, found)
+}
+
+// readRewriteInput returns the text to rewrite: stdin when the argument is "-" or input is piped,
+// otherwise the clipboard contents
+func readRewriteInput(args []string) (string, error) {
+	if len(args) > 1 || (len(args) == 1 && args[0] != "-") {
+		return "", errors.New("usage: songlink-cli rewrite [-]")
+	}
+
+	piped := false
+	if info, err := os.Stdin.Stat(); err == nil && info.Mode()&os.ModeCharDevice == 0 {
+		piped = true
+	}
+	if len(args) == 1 || piped {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return "", fmt.Errorf("error reading stdin: %w", err)
+		}
+		return string(data), nil
+	}
+
+	text, err := clipboard.ReadAll()
+	if err != nil {
+		return "", fmt.Errorf("error reading clipboard: %w", err)
+	}
+	return text, nil
+}
+
+// rewriteTemplate returns the template links are replaced with. Without a template flag that is the
+// song.link page URL; the config's default_template isn't used because it is usually meant for whole messages.
+func rewriteTemplate() (string, string, error) {
+	if *templateFlag == "" && *templateNameFlag == "" && !*xFlag && !*dFlag && !*sFlag {
+		return defaultTemplate, builtinTemplates[defaultTemplate], nil
+	}
+	return selectedTemplate()
+}
+
+// findURLs returns the distinct URLs in text, in the order they first appear
+func findURLs(text string) []string {
+	var urls []string
+	seen := make(map[string]bool)
+	for _, match := range urlPattern.FindAllString(text, -1) {
+		match = trimURLPunctuation(match)
+		if !seen[match] {
+			seen[match] = true
+			urls = append(urls, match)
+		}
+	}
+	return urls
+}
+
+// rewriteText replaces every URL in text that has an entry in replacements
+func rewriteText(text string, replacements map[string]string) string {
+	return urlPattern.ReplaceAllStringFunc(text, func(match string) string {
+		trimmed := trimURLPunctuation(match)
+		replacement, ok := replacements[trimmed]
+		if !ok {
+			return match
+		}
+		return replacement + match[len(trimmed):]
+	})
+}
+
+// trimURLPunctuation removes sentence punctuation that follows a URL in text
+func trimURLPunctuation(match string) string {
+	return strings.TrimRight(match, ".,;:!?*_~")
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFindURLs(t *testing.T) {
+	text := "New single: https://open.spotify.com/track/1?si=abc. Also on (https://music.apple.com/us/album/x/2?i=3) " +
+		"and <https://youtu.be/4>, again https://open.spotify.com/track/1?si=abc!"
+	expected := []string{
+		"https://open.spotify.com/track/1?si=abc",
+		"https://music.apple.com/us/album/x/2?i=3",
+		"https://youtu.be/4",
+	}
+	if urls := findURLs(text); strings.Join(urls, " ") != strings.Join(expected, " ") {
+		t.Errorf("findURLs() = %v; want %v", urls, expected)
+	}
+}
+
+func TestRewriteText(t *testing.T) {
+	text := "Listen: https://open.spotify.com/track/1?si=abc.\n- [Video](https://youtu.be/4)\n- https://example.com/blog"
+	replacements := map[string]string{
+		"https://open.spotify.com/track/1?si=abc": "https://song.link/s/1",
+		"https://youtu.be/4":                      "https://song.link/y/4",
+	}
+
+	expected := "Listen: https://song.link/s/1.\n- [Video](https://song.link/y/4)\n- https://example.com/blog"
+	if rewritten := rewriteText(text, replacements); rewritten != expected {
+		t.Errorf("rewriteText() = %q; want %q", rewritten, expected)
+	}
+}
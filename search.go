@@ -4,170 +4,150 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"strings"
 	"time"
 
-	"github.com/marcusziade/musickitkat"
-	"github.com/marcusziade/musickitkat/auth"
+	"github.com/marcusziade/songlink-cli.git/pkg/search"
 )
 
-// SearchType represents the type of search to perform
-type SearchType string
-
-const (
-	Song  SearchType = "song"
-	Album SearchType = "album"
-	Both  SearchType = "both"
-)
+// newSearchClient creates an Apple Music catalog client with the credentials from config
+func newSearchClient(config *Config) (*search.Client, error) {
+	return search.NewClient(search.Credentials{
+		TeamID:     config.TeamID,
+		KeyID:      config.KeyID,
+		PrivateKey: config.PrivateKey,
+		MusicID:    config.MusicID,
+	})
+}
 
-// MusicSearcher handles searching for music
-type MusicSearcher struct {
-	client *musickitkat.Client
+// resultPager fetches pages of search results as the user moves through them, keeping the ones already seen
+type resultPager struct {
+	fetch func(ctx context.Context, limit, offset int) (search.Page, error)
+	limit int
+	// offset is where the first page starts
+	offset int
+	pages  map[int]search.Page
 }
 
-// SearchResult represents a search result
-type SearchResult struct {
-	ID         string
-	Name       string
-	ArtistName string
-	Type       SearchType
-	URL        string
-	// ArtworkURL is the URL of the track's artwork image
-	ArtworkURL string
+// newResultPager pages through the results of searching for query, limit results of each type at a time
+// starting at offset. A limit of 0 uses the API default.
+func newResultPager(searcher *search.Client, query string, searchType search.Type, limit, offset int) *resultPager {
+	if limit == 0 {
+		limit = search.DefaultLimit
+	}
+	return &resultPager{
+		fetch: func(ctx context.Context, limit, offset int) (search.Page, error) {
+			return searcher.SearchPage(ctx, query, searchType, limit, offset)
+		},
+		limit:  limit,
+		offset: offset,
+		pages:  make(map[int]search.Page),
+	}
 }
 
-// NewMusicSearcher creates a new MusicSearcher
-func NewMusicSearcher(config *Config) (*MusicSearcher, error) {
-	if !config.ConfigExists {
-		return nil, errors.New("apple music api credentials not configured")
+// page returns the page starting at offset, searching only if it hasn't been fetched before
+func (p *resultPager) page(ctx context.Context, offset int) (search.Page, error) {
+	if page, ok := p.pages[offset]; ok {
+		return page, nil
 	}
 
-	developerToken, err := auth.NewDeveloperToken(
-		config.TeamID,
-		config.KeyID,
-		[]byte(config.PrivateKey),
-		config.MusicID,
-	)
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	page, err := p.fetch(ctx, p.limit, offset)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create developer token: %w", err)
+		return search.Page{}, fmt.Errorf("error searching: %w", err)
 	}
-
-	client := musickitkat.NewClient(
-		musickitkat.WithDeveloperToken(developerToken),
-	)
-
-	return &MusicSearcher{
-		client: client,
-	}, nil
+	p.pages[offset] = page
+	return page, nil
 }
 
-// Search searches for music by query and type
-func (ms *MusicSearcher) Search(ctx context.Context, query string, searchType SearchType) ([]SearchResult, error) {
-	var results []SearchResult
-	var searchTypes []string
-
-	switch searchType {
-	case Song:
-		searchTypes = []string{string(musickitkat.SearchTypesSongs)}
-	case Album:
-		searchTypes = []string{string(musickitkat.SearchTypesAlbums)}
-	case Both:
-		// Search both song and album types
-		searchTypes = []string{string(musickitkat.SearchTypesSongs), string(musickitkat.SearchTypesAlbums)}
-	default:
-		// Default to songs if type is invalid
-		searchTypes = []string{string(musickitkat.SearchTypesSongs)}
-	}
-
-	for _, st := range searchTypes {
-		searchResults, err := ms.client.Search.Search(ctx, query, []string{st}, nil)
+// DisplaySearchResults displays search results a page at a time and lets user select one.
+// Further pages are fetched when the user asks for them. Pages are numbered from the pager's offset, and going back
+// stops there. The list and prompts are written to w.
+func DisplaySearchResults(ctx context.Context, w io.Writer, pager *resultPager) (*search.Result, error) {
+	offset := pager.offset
+	for {
+		page, err := pager.page(ctx, offset)
 		if err != nil {
-			return nil, fmt.Errorf("failed to search %s: %w", st, err)
+			return nil, err
 		}
-
-		// Process songs
-		if st == string(musickitkat.SearchTypesSongs) && len(searchResults.Results.Songs.Data) > 0 {
-			for _, song := range searchResults.Results.Songs.Data {
-				// Build artwork URL with desired size (500x500)
-				artURL := song.Attributes.Artwork.URL
-				artURL = strings.ReplaceAll(artURL, "{w}", "500")
-				artURL = strings.ReplaceAll(artURL, "{h}", "500")
-				results = append(results, SearchResult{
-					ID:         song.ID,
-					Name:       song.Attributes.Name,
-					ArtistName: song.Attributes.ArtistName,
-					Type:       Song,
-					URL:        song.Attributes.URL,
-					ArtworkURL: artURL,
-				})
+		if len(page.Results) == 0 {
+			if offset == pager.offset {
+				return nil, errors.New("no results found")
 			}
+			// The previous page claimed more results, but there were none
+			fmt.Fprintln(w, "\nThere are no more results")
+			offset -= pager.limit
+			continue
 		}
 
-		// Process albums
-		if st == string(musickitkat.SearchTypesAlbums) && len(searchResults.Results.Albums.Data) > 0 {
-			for _, album := range searchResults.Results.Albums.Data {
-				// Build artwork URL with desired size (500x500)
-				artURL := album.Attributes.Artwork.URL
-				artURL = strings.ReplaceAll(artURL, "{w}", "500")
-				artURL = strings.ReplaceAll(artURL, "{h}", "500")
-				results = append(results, SearchResult{
-					ID:         album.ID,
-					Name:       album.Attributes.Name,
-					ArtistName: album.Attributes.ArtistName,
-					Type:       Album,
-					URL:        album.Attributes.URL,
-					ArtworkURL: artURL,
-				})
+		fmt.Fprintf(w, "\nSearch Results (page %d):\n", (offset-pager.offset)/pager.limit+1)
+		fmt.Fprintln(w, "----------------")
+
+		for i, result := range page.Results {
+			typeStr := "Song"
+			if result.Type == search.Album {
+				typeStr = "Album"
 			}
+			fmt.Fprintf(w, "%d. [%s] %s - %s\n", i+1, typeStr, result.Name, result.ArtistName)
 		}
-	}
 
-	return results, nil
-}
-
-// DisplaySearchResults displays search results and lets user select one
-func DisplaySearchResults(results []SearchResult) (*SearchResult, error) {
-	if len(results) == 0 {
-		return nil, errors.New("no results found")
-	}
-
-	fmt.Println("\nSearch Results:")
-	fmt.Println("----------------")
-
-	for i, result := range results {
-		typeStr := "Song"
-		if result.Type == Album {
-			typeStr = "Album"
+		prompt := fmt.Sprintf("\nSelect a result (1-%d", len(page.Results))
+		if page.More {
+			prompt += ", n = next page"
 		}
-		fmt.Printf("%d. [%s] %s - %s\n", i+1, typeStr, result.Name, result.ArtistName)
-	}
-
-	var choice int
-	fmt.Print("\nSelect a result (1-", len(results), "): ")
-	
-	// Create a scanner to read from stdin
-	var input string
-	fmt.Scanln(&input)
-	
-	// If input is empty or can't be parsed, default to first result
-	if input == "" {
-		fmt.Println("1 (automatic selection)")
-		choice = 1
-	} else {
-		_, err := fmt.Sscanf(input, "%d", &choice)
-		if err != nil || choice < 1 || choice > len(results) {
-			return nil, errors.New("invalid selection")
+		if offset > pager.offset {
+			prompt += ", p = previous page"
+		}
+		fmt.Fprint(w, prompt, "): ")
+
+		var input string
+		fmt.Scanln(&input)
+
+		switch strings.ToLower(input) {
+		case "":
+			// If input is empty, default to first result
+			fmt.Fprintln(w, "1 (automatic selection)")
+			return &page.Results[0], nil
+		case "n":
+			if !page.More {
+				fmt.Fprintln(w, "This is the last page")
+				continue
+			}
+			offset += pager.limit
+		case "p":
+			if offset == pager.offset {
+				fmt.Fprintln(w, "This is the first page")
+				continue
+			}
+			offset -= pager.limit
+		default:
+			var choice int
+			_, err := fmt.Sscanf(input, "%d", &choice)
+			if err != nil || choice < 1 || choice > len(page.Results) {
+				return nil, errors.New("invalid selection")
+			}
+			return &page.Results[choice-1], nil
 		}
 	}
-
-	return &results[choice-1], nil
 }
 
 // HandleSearch handles the search command
 // HandleSearch performs an Apple Music search, then handles user action (copy links/download).
 // outDir is the directory to save downloads, debug controls verbosity of external tools.
-func HandleSearch(query string, searchType SearchType, outDir string, debug bool) error {
+func HandleSearch(ctx context.Context, query string, searchType search.Type, limit, offset int, outDir string, debug bool) error {
+	format, err := ParseOutputFormat(*outputFlag)
+	if err != nil {
+		return err
+	}
+	// Structured output is printed on stdout, so the interactive parts go to stderr
+	var ui io.Writer = os.Stdout
+	if format != FormatText {
+		ui = os.Stderr
+	}
+
 	// Load config
 	config, err := LoadConfig()
 	if err != nil {
@@ -175,9 +155,9 @@ func HandleSearch(query string, searchType SearchType, outDir string, debug bool
 	}
 
 	// Check if config exists, if not, run onboarding
-	if !config.ConfigExists {
-		fmt.Println("Apple Music API credentials not found. Let's set them up.")
-		err = RunOnboarding()
+	if !config.HasAppleMusicCredentials() {
+		fmt.Fprintln(ui, "Apple Music API credentials not found. Let's set them up.")
+		err = RunOnboarding(ui)
 		if err != nil {
 			return fmt.Errorf("error during onboarding: %w", err)
 		}
@@ -190,118 +170,135 @@ func HandleSearch(query string, searchType SearchType, outDir string, debug bool
 	}
 
 	// Create music searcher
-	searcher, err := NewMusicSearcher(config)
+	searcher, err := newSearchClient(config)
 	if err != nil {
 		return fmt.Errorf("error creating music searcher: %w", err)
 	}
 
+	// Search for music. Later pages are fetched when they are asked for.
+	pager := newResultPager(searcher, query, searchType, limit, offset)
+	return handleSearchResults(ctx, ui, pager, format, outDir, debug)
+}
+
+// handleSearchResults lets the user select one of the results of pager, then prints its links in a structured
+// format or, for text output, asks what to do with it. The list and prompts are written to ui.
+func handleSearchResults(ctx context.Context, ui io.Writer, pager *resultPager, format OutputFormat, outDir string, debug bool) error {
 	// Start loading indicator
 	stopLoading := make(chan bool)
 	go func() {
-		loadingIndicator(stopLoading)
+		loadingIndicator(ui, stopLoading)
 	}()
 
-	// Search for music
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
-
-	results, err := searcher.Search(ctx, query, searchType)
+	_, err := pager.page(ctx, pager.offset)
 
 	// Stop loading indicator
 	stopLoading <- true
 
 	if err != nil {
-		return fmt.Errorf("error searching: %w", err)
+		return err
 	}
 
-   // Display results and get selection
-   selected, err := DisplaySearchResults(results)
+	// Display results and get selection
+	selected, err := DisplaySearchResults(ctx, ui, pager)
 	if err != nil {
 		return fmt.Errorf("error selecting result: %w", err)
 	}
 
-   fmt.Printf("\nSelected: %s - %s\n", selected.Name, selected.ArtistName)
-   // Prompt user for next action
-   fmt.Println("\nWhat would you like to do?")
-   fmt.Println("1) Copy song.link + Spotify URL to clipboard")
-   fmt.Println("2) Download MP3")
-   fmt.Println("3) Download MP4 (video with artwork)")
-   fmt.Print("Enter choice (1-3, default 1): ")
-   var choice string
-   fmt.Scanln(&choice)
-   switch choice {
-   case "", "1":
-       // Copy links
-       if err := GetLinks(selected.URL); err != nil {
-           return fmt.Errorf("error getting links: %w", err)
-       }
-   case "2":
-       // Download MP3
-       fmt.Print("Downloading MP3... ")
-       path, err := DownloadTrack(selected.Name, selected.ArtistName, selected.ArtworkURL, "mp3", outDir, debug)
-       if err != nil {
-           return fmt.Errorf("error downloading mp3: %w", err)
-       }
-       fmt.Printf("Done. Saved to %s\n", path)
-   case "3":
-       // Download MP4
-       fmt.Print("Downloading MP4... ")
-       path, err := DownloadTrack(selected.Name, selected.ArtistName, selected.ArtworkURL, "mp4", outDir, debug)
-       if err != nil {
-           return fmt.Errorf("error downloading mp4: %w", err)
-       }
-       fmt.Printf("Done. Saved to %s\n", path)
-   default:
-       for {
-           fmt.Println("Invalid choice. Please enter a valid option (1-3, default 1):")
-           fmt.Print("Enter choice (1-3, default 1): ")
-           fmt.Scanln(&choice)
-           if choice == "" || choice == "1" || choice == "2" || choice == "3" {
-               break
-           }
-       }
-       switch choice {
-       case "", "1":
-           // Copy links
-           if err := GetLinks(selected.URL); err != nil {
-               return fmt.Errorf("error getting links: %w", err)
-           }
-       case "2":
-           // Download MP3
-           fmt.Print("Downloading MP3... ")
-           path, err := DownloadTrack(selected.Name, selected.ArtistName, selected.ArtworkURL, "mp3", outDir, debug)
-           if err != nil {
-               return fmt.Errorf("error downloading mp3: %w", err)
-           }
-           fmt.Printf("Done. Saved to %s\n", path)
-       case "3":
-           // Download MP4
-           fmt.Print("Downloading MP4... ")
-           path, err := DownloadTrack(selected.Name, selected.ArtistName, selected.ArtworkURL, "mp4", outDir, debug)
-           if err != nil {
-               return fmt.Errorf("error downloading mp4: %w", err)
-           }
-           fmt.Printf("Done. Saved to %s\n", path)
-       }
-   }
-   return nil
+	// Structured output skips the action prompt and prints the selection's links
+	if format != FormatText {
+		if err := GetLinks(ctx, selected.URL); err != nil {
+			return fmt.Errorf("error getting links: %w", err)
+		}
+		return nil
+	}
+
+	fmt.Printf("\nSelected: %s - %s\n", selected.Name, selected.ArtistName)
+	// Prompt user for next action
+	fmt.Println("\nWhat would you like to do?")
+	fmt.Println("1) Copy song.link + preferred platform URL to clipboard")
+	fmt.Println("2) Download MP3")
+	fmt.Println("3) Download MP4 (video with artwork)")
+	fmt.Print("Enter choice (1-3, default 1): ")
+	var choice string
+	fmt.Scanln(&choice)
+	switch choice {
+	case "", "1":
+		// Copy links
+		if err := GetLinks(ctx, selected.URL); err != nil {
+			return fmt.Errorf("error getting links: %w", err)
+		}
+	case "2":
+		// Download MP3
+		fmt.Print("Downloading MP3... ")
+		path, err := DownloadTrack(ctx, selected.Name, selected.ArtistName, selected.ArtworkURL, "mp3", outDir, debug)
+		if err != nil {
+			return fmt.Errorf("error downloading mp3: %w", err)
+		}
+		fmt.Printf("Done. Saved to %s\n", path)
+	case "3":
+		// Download MP4
+		fmt.Print("Downloading MP4... ")
+		path, err := DownloadTrack(ctx, selected.Name, selected.ArtistName, selected.ArtworkURL, "mp4", outDir, debug)
+		if err != nil {
+			return fmt.Errorf("error downloading mp4: %w", err)
+		}
+		fmt.Printf("Done. Saved to %s\n", path)
+	default:
+		for {
+			fmt.Println("Invalid choice. Please enter a valid option (1-3, default 1):")
+			fmt.Print("Enter choice (1-3, default 1): ")
+			fmt.Scanln(&choice)
+			if choice == "" || choice == "1" || choice == "2" || choice == "3" {
+				break
+			}
+		}
+		switch choice {
+		case "", "1":
+			// Copy links
+			if err := GetLinks(ctx, selected.URL); err != nil {
+				return fmt.Errorf("error getting links: %w", err)
+			}
+		case "2":
+			// Download MP3
+			fmt.Print("Downloading MP3... ")
+			path, err := DownloadTrack(ctx, selected.Name, selected.ArtistName, selected.ArtworkURL, "mp3", outDir, debug)
+			if err != nil {
+				return fmt.Errorf("error downloading mp3: %w", err)
+			}
+			fmt.Printf("Done. Saved to %s\n", path)
+		case "3":
+			// Download MP4
+			fmt.Print("Downloading MP4... ")
+			path, err := DownloadTrack(ctx, selected.Name, selected.ArtistName, selected.ArtworkURL, "mp4", outDir, debug)
+			if err != nil {
+				return fmt.Errorf("error downloading mp4: %w", err)
+			}
+			fmt.Printf("Done. Saved to %s\n", path)
+		}
+	}
+	return nil
 }
 
-// RunOnboarding guides the user through setting up Apple Music API credentials
-func RunOnboarding() error {
-	config := &Config{}
+// RunOnboarding guides the user through setting up Apple Music API credentials, writing the instructions and prompts to w
+func RunOnboarding(w io.Writer) error {
+	// Start from the existing config so that non-credential settings are kept
+	config, err := LoadConfig()
+	if err != nil {
+		return fmt.Errorf("error loading config: %w", err)
+	}
+	config.TeamID, config.KeyID, config.MusicID, config.PrivateKey = "", "", "", ""
 
-	fmt.Println("\n========== Apple Music API Setup ==========")
-	fmt.Println("To use the search feature, you need Apple Music API credentials.")
-	fmt.Println("Follow these steps to get them:")
-	fmt.Println("1. Sign in to your Apple Developer account at https://developer.apple.com")
-	fmt.Println("2. Go to Certificates, Identifiers & Profiles")
-	fmt.Println("3. Under Keys, create a new key with MusicKit enabled")
-	fmt.Println("4. Note down the Key ID, Team ID, and download the private key (.p8) file")
-	fmt.Println("\nYou'll need to enter these values below:")
+	fmt.Fprintln(w, "\n========== Apple Music API Setup ==========")
+	fmt.Fprintln(w, "To use the search feature, you need Apple Music API credentials.")
+	fmt.Fprintln(w, "Follow these steps to get them:")
+	fmt.Fprintln(w, "1. Sign in to your Apple Developer account at https://developer.apple.com")
+	fmt.Fprintln(w, "2. Go to Certificates, Identifiers & Profiles")
+	fmt.Fprintln(w, "3. Under Keys, create a new key with MusicKit enabled")
+	fmt.Fprintln(w, "4. Note down the Key ID, Team ID, and download the private key (.p8) file")
+	fmt.Fprintln(w, "\nYou'll need to enter these values below:")
 
 	// Get Team ID
-	fmt.Print("\nTeam ID: ")
+	fmt.Fprint(w, "\nTeam ID: ")
 	fmt.Scanln(&config.TeamID)
 	config.TeamID = strings.TrimSpace(config.TeamID)
 	if config.TeamID == "" {
@@ -309,7 +306,7 @@ func RunOnboarding() error {
 	}
 
 	// Get Key ID
-	fmt.Print("Key ID: ")
+	fmt.Fprint(w, "Key ID: ")
 	fmt.Scanln(&config.KeyID)
 	config.KeyID = strings.TrimSpace(config.KeyID)
 	if config.KeyID == "" {
@@ -317,7 +314,7 @@ func RunOnboarding() error {
 	}
 
 	// Get Music ID (usually same as Team ID)
-	fmt.Print("Music ID (usually same as Team ID): ")
+	fmt.Fprint(w, "Music ID (usually same as Team ID): ")
 	fmt.Scanln(&config.MusicID)
 	config.MusicID = strings.TrimSpace(config.MusicID)
 	if config.MusicID == "" {
@@ -325,7 +322,7 @@ func RunOnboarding() error {
 	}
 
 	// Get Private Key path
-	fmt.Println("\nPath to your .p8 private key file:")
+	fmt.Fprintln(w, "\nPath to your .p8 private key file:")
 	var keyPath string
 	fmt.Scanln(&keyPath)
 	keyPath = strings.TrimSpace(keyPath)
@@ -351,6 +348,6 @@ func RunOnboarding() error {
 		return fmt.Errorf("error saving config: %w", err)
 	}
 
-	fmt.Println("\n✅ Apple Music API credentials saved successfully!")
+	fmt.Fprintln(w, "\n✅ Apple Music API credentials saved successfully!")
 	return nil
-}
\ No newline at end of file
+}
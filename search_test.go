@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/marcusziade/songlink-cli.git/pkg/search"
+)
+
+// fakePager pages through total songs named "Song 1", "Song 2" and so on, counting the searches
+func fakePager(total, limit, offset int, searches *int) *resultPager {
+	return &resultPager{
+		fetch: func(ctx context.Context, limit, offset int) (search.Page, error) {
+			*searches++
+			var page search.Page
+			for i := offset; i < min(offset+limit, total); i++ {
+				page.Results = append(page.Results, search.Result{
+					ID:   fmt.Sprint(i + 1),
+					Name: fmt.Sprintf("Song %d", i+1),
+					Type: search.Song,
+					URL:  fmt.Sprintf("https://music.apple.com/us/song/%d", i+1),
+				})
+			}
+			page.More = offset+limit < total
+			return page, nil
+		},
+		limit:  limit,
+		offset: offset,
+		pages:  make(map[int]search.Page),
+	}
+}
+
+// withStdin runs f with input on stdin
+func withStdin(t *testing.T, input string, f func()) {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.WriteString(input); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	stdin := os.Stdin
+	os.Stdin = r
+	defer func() {
+		os.Stdin = stdin
+		r.Close()
+	}()
+	f()
+}
+
+func TestDisplaySearchResultsPaging(t *testing.T) {
+	tests := []struct {
+		name     string
+		offset   int
+		input    string
+		expected string
+		searches int
+	}{
+		{"first page", 0, "2\n", "Song 2", 1},
+		{"default", 0, "\n", "Song 1", 1},
+		{"next page", 0, "n\n1\n", "Song 4", 2},
+		{"back is not fetched again", 0, "n\np\n3\n", "Song 3", 2},
+		{"no page after the last", 0, "n\nn\nn\n1\n", "Song 7", 3},
+		{"no page before the first", 0, "p\n1\n", "Song 1", 1},
+		{"no page before the offset", 2, "p\n1\n", "Song 3", 1},
+		{"pages from the offset", 2, "n\np\n1\n", "Song 3", 2},
+	}
+	for _, tt := range tests {
+		searches := 0
+		var selected *search.Result
+		var err error
+		withStdin(t, tt.input, func() {
+			selected, err = DisplaySearchResults(context.Background(), io.Discard, fakePager(7, 3, tt.offset, &searches))
+		})
+		if err != nil {
+			t.Errorf("%s: DisplaySearchResults returned an unexpected error: %v", tt.name, err)
+			continue
+		}
+		if selected.Name != tt.expected {
+			t.Errorf("%s: selected %q; want %q", tt.name, selected.Name, tt.expected)
+		}
+		if searches != tt.searches {
+			t.Errorf("%s: searched %d times; want %d", tt.name, searches, tt.searches)
+		}
+	}
+
+	var list bytes.Buffer
+	searches := 0
+	withStdin(t, "n\n1\n", func() {
+		DisplaySearchResults(context.Background(), &list, fakePager(7, 3, 2, &searches))
+	})
+	if !strings.Contains(list.String(), "Search Results (page 1)") || !strings.Contains(list.String(), "Search Results (page 2)") {
+		t.Errorf("pages after -offset aren't numbered from 1:\n%s", list.String())
+	}
+}
+
+func TestDisplaySearchResultsErrors(t *testing.T) {
+	searches := 0
+	withStdin(t, "", func() {
+		if _, err := DisplaySearchResults(context.Background(), io.Discard, fakePager(0, 3, 0, &searches)); err == nil {
+			t.Error("DisplaySearchResults should return an error without results")
+		}
+	})
+	withStdin(t, "4\n", func() {
+		if _, err := DisplaySearchResults(context.Background(), io.Discard, fakePager(7, 3, 0, &searches)); err == nil {
+			t.Error("DisplaySearchResults should reject a number that isn't on the page")
+		}
+	})
+}
+
+func TestHandleSearchResultsStructured(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"pageUrl": "https://song.link/i/2", "linksByPlatform": {"spotify": {"url": "https://open.spotify.com/track/2"}}}`)
+	}))
+	defer api.Close()
+	t.Setenv("SONGLINK_API_BASE_URL", api.URL)
+	output := *outputFlag
+	*outputFlag = "json"
+	defer func() { *outputFlag = output }()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	var ui bytes.Buffer
+	searches := 0
+	withStdin(t, "2\n", func() {
+		err = handleSearchResults(context.Background(), &ui, fakePager(7, 3, 0, &searches), FormatJSON, "downloads", false)
+	})
+	os.Stdout = stdout
+	w.Close()
+	printed, _ := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("handleSearchResults returned an unexpected error: %v", err)
+	}
+
+	var result LinkResult
+	if err := json.Unmarshal(printed, &result); err != nil {
+		t.Fatalf("stdout is not only the JSON document: %v\n%s", err, printed)
+	}
+	if result.PageURL != "https://song.link/i/2" {
+		t.Errorf("printed the links of %s; want https://song.link/i/2", result.PageURL)
+	}
+	if !strings.Contains(ui.String(), "Search Results") || !strings.Contains(ui.String(), "Select a result") {
+		t.Errorf("the result list was not written to the UI writer:\n%s", ui.String())
+	}
+}
@@ -0,0 +1,565 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/marcusziade/songlink-cli.git/pkg/download"
+	"github.com/marcusziade/songlink-cli.git/pkg/search"
+	"github.com/marcusziade/songlink-cli.git/pkg/songlink"
+)
+
+// shutdownTimeout is how long in-flight requests and stopped downloads get to finish when the server stops
+const shutdownTimeout = 10 * time.Second
+
+// finishedJobTTL is how long finished downloads can be looked up before they are forgotten
+const finishedJobTTL = time.Hour
+
+// limiterSweepInterval is how often the rate limiter forgets clients whose buckets have filled up again
+const limiterSweepInterval = time.Minute
+
+// apiServer serves link resolution, search and downloads over HTTP
+type apiServer struct {
+	client     *songlink.Client
+	country    string
+	platforms  []string
+	keepLocale bool
+	// searcher is nil when Apple Music credentials aren't configured
+	searcher *search.Client
+	outDir   string
+	jobs     *downloadJobs
+	limiter  *clientRateLimiter
+}
+
+// executeServe handles the serve subcommand
+func executeServe(ctx context.Context, args []string) error {
+	serveCmd := flag.NewFlagSet("serve", flag.ExitOnError)
+	addrFlag := serveCmd.String("addr", "localhost:8080", "Address to listen on; use :8080 to accept connections from other machines")
+	outFlag := serveCmd.String("out", "downloads", "Output directory for downloaded files")
+	rateFlag := serveCmd.Int("rate", 60, "Requests per minute allowed from each client; 0 disables the limit")
+	downloadWorkersFlag := serveCmd.Int("download-workers", 2, "Number of downloads to run at the same time")
+
+	if err := serveCmd.Parse(args); err != nil {
+		return err
+	}
+	if *downloadWorkersFlag < 1 {
+		return errors.New("-download-workers must be at least 1")
+	}
+
+	s, err := newAPIServer(ctx, *outFlag, *rateFlag, *downloadWorkersFlag)
+	if err != nil {
+		return err
+	}
+	if s.searcher == nil {
+		log.Println("Apple Music API credentials not configured; /search and /downloads are disabled")
+	}
+
+	server := &http.Server{
+		Addr:              *addrFlag,
+		Handler:           s.handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	serverErr := make(chan error, 1)
+	go func() {
+		log.Printf("Listening on %s", *addrFlag)
+		serverErr <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serverErr:
+		return fmt.Errorf("error running server: %w", err)
+	case <-ctx.Done():
+	}
+
+	log.Println("Shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("error shutting down server: %w", err)
+	}
+	// The downloads were stopped with ctx; wait for them to clean up
+	if err := s.jobs.wait(shutdownCtx); err != nil {
+		return fmt.Errorf("error waiting for downloads to stop: %w", err)
+	}
+	return nil
+}
+
+// newAPIServer creates a server using the configured API settings, country and platforms.
+// Downloads are stopped when ctx is done.
+func newAPIServer(ctx context.Context, outDir string, requestsPerMinute, downloadWorkers int) (*apiServer, error) {
+	s := &apiServer{outDir: outDir, jobs: newDownloadJobs(ctx, downloadWorkers)}
+	if requestsPerMinute > 0 {
+		s.limiter = newClientRateLimiter(requestsPerMinute)
+	}
+
+	var err error
+	if s.client, err = newSonglinkClient(); err != nil {
+		return nil, err
+	}
+	if s.country, err = configuredCountry(); err != nil {
+		return nil, err
+	}
+	if s.platforms, err = configuredPlatforms(); err != nil {
+		return nil, err
+	}
+	if s.keepLocale, err = keepLocale(); err != nil {
+		return nil, err
+	}
+
+	config, err := LoadConfig()
+	if err != nil {
+		return nil, fmt.Errorf("error loading config: %w", err)
+	}
+	if config.HasAppleMusicCredentials() {
+		if s.searcher, err = newSearchClient(config); err != nil {
+			return nil, fmt.Errorf("error creating music searcher: %w", err)
+		}
+	}
+	return s, nil
+}
+
+// handler returns the routes wrapped in the logging and rate limiting middleware
+func (s *apiServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /links", s.handleLinks)
+	mux.HandleFunc("GET /search", s.handleSearch)
+	mux.HandleFunc("POST /downloads", s.handleCreateDownload)
+	mux.HandleFunc("GET /downloads/{id}", s.handleGetDownload)
+
+	var handler http.Handler = mux
+	if s.limiter != nil {
+		handler = s.limiter.middleware(handler)
+	}
+	return logRequests(handler)
+}
+
+// handleLinks resolves ?url=, or ?platform=&type=&id=, and responds with a LinkResult
+func (s *apiServer) handleLinks(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := linkContext(r.Context())
+	defer cancel()
+	params := r.URL.Query()
+
+	country := s.country
+	if value := params.Get("country"); value != "" {
+		var err error
+		if country, err = songlink.ParseCountry(value); err != nil {
+			writeJSONError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+	platforms := s.platforms
+	if value := params.Get("platforms"); value != "" {
+		var err error
+		if platforms, err = songlink.ParsePlatforms(value); err != nil {
+			writeJSONError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	var query songlink.Query
+	inputLabel := params.Get("url")
+	switch {
+	case inputLabel != "":
+		input, err := s.client.NormalizeURL(ctx, inputLabel)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, err)
+			return
+		}
+		query = songlink.Query{URL: input.URL}
+	case params.Get("id") != "":
+		var err error
+		if query, err = songlink.ParseIDQuery(params.Get("platform"), params.Get("type"), params.Get("id")); err != nil {
+			writeJSONError(w, http.StatusBadRequest, err)
+			return
+		}
+		inputLabel = query.String()
+	default:
+		writeJSONError(w, http.StatusBadRequest, errors.New("the url parameter is required"))
+		return
+	}
+	query.Country = country
+
+	response, err := fetchLinks(ctx, s.client, query)
+	if err != nil {
+		status := http.StatusBadGateway
+		var apiErr *songlink.APIError
+		if errors.As(err, &apiErr) && apiErr.Unsupported() {
+			status = http.StatusNotFound
+		} else if errors.Is(err, songlink.ErrTimeout) {
+			status = http.StatusGatewayTimeout
+		}
+		writeJSONError(w, status, err)
+		return
+	}
+	writeJSONResponse(w, http.StatusOK, NewLinkResult(inputLabel, response, platforms, s.keepLocale))
+}
+
+// handleSearch searches the Apple Music catalog for ?q= and ?type=song|album|both
+func (s *apiServer) handleSearch(w http.ResponseWriter, r *http.Request) {
+	if s.searcher == nil {
+		writeJSONError(w, http.StatusServiceUnavailable, errors.New("apple music api credentials not configured"))
+		return
+	}
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		writeJSONError(w, http.StatusBadRequest, errors.New("the q parameter is required"))
+		return
+	}
+	searchType, err := parseSearchType(r.URL.Query().Get("type"))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+	results, err := s.searcher.Search(ctx, query, searchType)
+	if err != nil {
+		writeJSONError(w, http.StatusBadGateway, err)
+		return
+	}
+	if results == nil {
+		results = []search.Result{}
+	}
+	writeJSONResponse(w, http.StatusOK, results)
+}
+
+// downloadRequest is the body of POST /downloads
+type downloadRequest struct {
+	Query  string `json:"query"`
+	Type   string `json:"type"`
+	Format string `json:"format"`
+}
+
+// handleCreateDownload searches for the requested track and queues a download of the best match
+func (s *apiServer) handleCreateDownload(w http.ResponseWriter, r *http.Request) {
+	if s.searcher == nil {
+		writeJSONError(w, http.StatusServiceUnavailable, errors.New("apple music api credentials not configured"))
+		return
+	}
+
+	var request downloadRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16)).Decode(&request); err != nil {
+		writeJSONError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return
+	}
+	request.Query = strings.TrimSpace(request.Query)
+	if request.Query == "" {
+		writeJSONError(w, http.StatusBadRequest, errors.New("query is required"))
+		return
+	}
+	if request.Format == "" {
+		request.Format = "mp3"
+	}
+	if request.Format != "mp3" && request.Format != "mp4" {
+		writeJSONError(w, http.StatusBadRequest, fmt.Errorf("invalid format %q (use mp3 or mp4)", request.Format))
+		return
+	}
+	searchType, err := parseSearchType(request.Type)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+	results, err := s.searcher.Search(ctx, request.Query, searchType)
+	if err != nil {
+		writeJSONError(w, http.StatusBadGateway, err)
+		return
+	}
+	if len(results) == 0 {
+		writeJSONError(w, http.StatusNotFound, errors.New("no results found"))
+		return
+	}
+
+	job := s.jobs.start(results[0], request.Format, s.outDir)
+	w.Header().Set("Location", "/downloads/"+job.ID)
+	writeJSONResponse(w, http.StatusAccepted, job)
+}
+
+// handleGetDownload reports the status of a download job
+func (s *apiServer) handleGetDownload(w http.ResponseWriter, r *http.Request) {
+	job, ok := s.jobs.get(r.PathValue("id"))
+	if !ok {
+		writeJSONError(w, http.StatusNotFound, errors.New("download not found"))
+		return
+	}
+	writeJSONResponse(w, http.StatusOK, job)
+}
+
+// parseSearchType parses the type parameter of search and download requests, defaulting to songs
+func parseSearchType(value string) (search.Type, error) {
+	switch search.Type(strings.ToLower(value)) {
+	case "", search.Song:
+		return search.Song, nil
+	case search.Album:
+		return search.Album, nil
+	case search.Both:
+		return search.Both, nil
+	default:
+		return "", fmt.Errorf("invalid type %q (use song, album or both)", value)
+	}
+}
+
+func writeJSONResponse(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("error encoding response: %v", err)
+	}
+}
+
+func writeJSONError(w http.ResponseWriter, status int, err error) {
+	writeJSONResponse(w, status, map[string]string{"error": err.Error()})
+}
+
+// DownloadJob is the state of a download submitted to the server
+type DownloadJob struct {
+	ID       string     `json:"id"`
+	Title    string     `json:"title"`
+	Artist   string     `json:"artist"`
+	Format   string     `json:"format"`
+	Status   string     `json:"status"`
+	Path     string     `json:"path,omitempty"`
+	Error    string     `json:"error,omitempty"`
+	Created  time.Time  `json:"created"`
+	Finished *time.Time `json:"finished,omitempty"`
+}
+
+// Download job statuses
+const (
+	JobQueued  = "queued"
+	JobRunning = "running"
+	JobDone    = "done"
+	JobFailed  = "failed"
+)
+
+// downloadJobs runs downloads in the background, a limited number at a time
+type downloadJobs struct {
+	// ctx stops queued and running downloads when it is done
+	ctx     context.Context
+	running sync.WaitGroup
+	mu      sync.Mutex
+	jobs    map[string]*DownloadJob
+	// slots limits how many downloads run at the same time
+	slots chan struct{}
+	// ttl is how long finished jobs are kept
+	ttl time.Duration
+	// download downloads with yt-dlp; tests replace it
+	download func(ctx context.Context, request download.Request) (string, error)
+}
+
+func newDownloadJobs(ctx context.Context, workers int) *downloadJobs {
+	return &downloadJobs{
+		ctx:      ctx,
+		jobs:     make(map[string]*DownloadJob),
+		slots:    make(chan struct{}, workers),
+		ttl:      finishedJobTTL,
+		download: download.New().Download,
+	}
+}
+
+// start queues a download of result and returns a snapshot of the new job
+func (d *downloadJobs) start(result search.Result, format, outDir string) DownloadJob {
+	job := &DownloadJob{
+		ID:      newJobID(),
+		Title:   result.Name,
+		Artist:  result.ArtistName,
+		Format:  format,
+		Status:  JobQueued,
+		Created: time.Now(),
+	}
+	d.mu.Lock()
+	d.jobs[job.ID] = job
+	snapshot := *job
+	d.mu.Unlock()
+
+	d.running.Add(1)
+	go func() {
+		defer d.running.Done()
+
+		var path string
+		err := errors.New("the server stopped before the download started")
+		select {
+		case d.slots <- struct{}{}:
+			// A slot can free up as the server stops
+			if d.ctx.Err() == nil {
+				d.update(job.ID, func(job *DownloadJob) { job.Status = JobRunning })
+				path, err = d.download(d.ctx, download.Request{
+					Song:       result.Name,
+					Artist:     result.ArtistName,
+					ArtworkURL: result.ArtworkURL,
+					Format:     format,
+					OutDir:     outDir,
+				})
+			}
+			<-d.slots
+		case <-d.ctx.Done():
+		}
+
+		d.update(job.ID, func(job *DownloadJob) {
+			finished := time.Now()
+			job.Finished = &finished
+			if err != nil {
+				job.Status = JobFailed
+				job.Error = err.Error()
+				return
+			}
+			job.Status = JobDone
+			job.Path = path
+		})
+
+		// Forget the job once it has been finished for a while so that the map doesn't grow forever
+		time.AfterFunc(d.ttl, func() {
+			d.mu.Lock()
+			delete(d.jobs, job.ID)
+			d.mu.Unlock()
+		})
+	}()
+
+	return snapshot
+}
+
+// get returns a snapshot of the job with id
+func (d *downloadJobs) get(id string) (DownloadJob, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	job, ok := d.jobs[id]
+	if !ok {
+		return DownloadJob{}, false
+	}
+	return *job, true
+}
+
+// wait waits until the downloads have stopped, or ctx is done
+func (d *downloadJobs) wait(ctx context.Context) error {
+	stopped := make(chan struct{})
+	go func() {
+		d.running.Wait()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (d *downloadJobs) update(id string, fn func(job *DownloadJob)) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	fn(d.jobs[id])
+}
+
+func newJobID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// clientRateLimiter is an in-memory token bucket per client IP address
+type clientRateLimiter struct {
+	mu       sync.Mutex
+	capacity float64
+	// refill is the number of tokens added per second
+	refill  float64
+	buckets map[string]*clientBucket
+	// swept is when full buckets were last removed
+	swept time.Time
+}
+
+type clientBucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+func newClientRateLimiter(requestsPerMinute int) *clientRateLimiter {
+	return &clientRateLimiter{
+		capacity: float64(requestsPerMinute),
+		refill:   float64(requestsPerMinute) / 60,
+		buckets:  make(map[string]*clientBucket),
+	}
+}
+
+// allow takes a token from the client's bucket. If there is none it returns how long until the next one.
+func (l *clientRateLimiter) allow(client string, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	bucket, ok := l.buckets[client]
+	if !ok {
+		bucket = &clientBucket{tokens: l.capacity, updated: now}
+		l.buckets[client] = bucket
+	}
+	bucket.tokens = math.Min(l.capacity, bucket.tokens+now.Sub(bucket.updated).Seconds()*l.refill)
+	bucket.updated = now
+
+	// Forget clients whose buckets have filled up again so that the map doesn't grow forever
+	if now.Sub(l.swept) >= limiterSweepInterval {
+		for key, other := range l.buckets {
+			if key != client && other.tokens+now.Sub(other.updated).Seconds()*l.refill >= l.capacity {
+				delete(l.buckets, key)
+			}
+		}
+		l.swept = now
+	}
+
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - bucket.tokens) / l.refill * float64(time.Second))
+}
+
+// middleware rejects requests from clients that are over their limit with 429 Too Many Requests
+func (l *clientRateLimiter) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		client, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			client = r.RemoteAddr
+		}
+		if ok, wait := l.allow(client, time.Now()); !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			writeJSONError(w, http.StatusTooManyRequests, errors.New("rate limit exceeded"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// statusRecorder remembers the status code written by a handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// logRequests logs the method, path, status and duration of every request
+func logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r)
+		log.Printf("%s %s %s %d %s", r.RemoteAddr, r.Method, r.URL.RequestURI(), recorder.status, time.Since(start).Round(time.Millisecond))
+	})
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/marcusziade/songlink-cli.git/pkg/download"
+	"github.com/marcusziade/songlink-cli.git/pkg/search"
+	"github.com/marcusziade/songlink-cli.git/pkg/songlink"
+)
+
+func TestServeLinks(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("url") != "https://open.spotify.com/track/2Xtsv7BUMrNodQWH2JPOc0" {
+			t.Errorf("unexpected request to the mock server: %s", r.URL)
+		}
+		fmt.Fprintln(w, `{"pageUrl": "https://song.link/s/2Xtsv7BUMrNodQWH2JPOc0", "linksByPlatform": {"tidal": {"url": "https://tidal.com/track/1"}}}`)
+	}))
+	defer api.Close()
+
+	s := &apiServer{client: songlink.NewClient(songlink.WithBaseURL(api.URL)), jobs: newDownloadJobs(context.Background(), 1)}
+	server := httptest.NewServer(s.handler())
+	defer server.Close()
+
+	response, err := http.Get(server.URL + "/links?url=https://open.spotify.com/track/2Xtsv7BUMrNodQWH2JPOc0%3Fsi%3Dabc")
+	if err != nil {
+		t.Fatalf("GET /links returned an unexpected error: %v", err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("GET /links returned status %s", response.Status)
+	}
+	var result LinkResult
+	if err := json.NewDecoder(response.Body).Decode(&result); err != nil {
+		t.Fatalf("GET /links returned invalid JSON: %v", err)
+	}
+	if result.PageURL != "https://song.link/s/2Xtsv7BUMrNodQWH2JPOc0" || result.Link("tidal") != "https://tidal.com/track/1" {
+		t.Errorf("GET /links returned an unexpected result: %+v", result)
+	}
+
+	for path, status := range map[string]int{
+		"/links":                              http.StatusBadRequest,
+		"/links?url=https://example.com/a":    http.StatusBadRequest,
+		"/search?q=caravan":                   http.StatusServiceUnavailable,
+		"/downloads/missing":                  http.StatusNotFound,
+		"/links?platform=spotify&id=1&type=x": http.StatusBadRequest,
+	} {
+		response, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatalf("GET %s returned an unexpected error: %v", path, err)
+		}
+		response.Body.Close()
+		if response.StatusCode != status {
+			t.Errorf("GET %s returned status %d; want %d", path, response.StatusCode, status)
+		}
+	}
+}
+
+func TestClientRateLimiter(t *testing.T) {
+	limiter := newClientRateLimiter(2)
+	now := time.Now()
+
+	for i := 0; i < 2; i++ {
+		if ok, _ := limiter.allow("192.0.2.1", now); !ok {
+			t.Fatalf("request %d was rejected within the burst", i+1)
+		}
+	}
+	if ok, wait := limiter.allow("192.0.2.1", now); ok || wait <= 0 {
+		t.Errorf("request over the limit was allowed (wait %s)", wait)
+	}
+	if ok, _ := limiter.allow("192.0.2.2", now); !ok {
+		t.Errorf("another client was limited by the first one's requests")
+	}
+	if ok, _ := limiter.allow("192.0.2.1", now.Add(30*time.Second)); !ok {
+		t.Errorf("request was rejected after the bucket refilled")
+	}
+}
+
+func TestDownloadJobs(t *testing.T) {
+	jobs := newDownloadJobs(context.Background(), 1)
+	jobs.ttl = 50 * time.Millisecond
+	done := make(chan struct{})
+	jobs.download = func(ctx context.Context, request download.Request) (string, error) {
+		defer close(done)
+		return request.OutDir + "/" + request.Artist + " - " + request.Song + "." + request.Format, nil
+	}
+
+	job := jobs.start(search.Result{Name: "Caravan", ArtistName: "Duke Ellington"}, "mp3", "downloads")
+	if job.Status != JobQueued {
+		t.Errorf("new job has status %q; want %q", job.Status, JobQueued)
+	}
+	<-done
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		job, _ = jobs.get(job.ID)
+		if job.Status == JobDone || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if job.Status != JobDone || job.Path != "downloads/Duke Ellington - Caravan.mp3" {
+		t.Errorf("finished job = %+v", job)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if _, ok := jobs.get(job.ID); ok {
+		t.Errorf("finished job was kept after its TTL")
+	}
+}
+
+func TestDownloadJobsStop(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	jobs := newDownloadJobs(ctx, 1)
+	started := make(chan struct{})
+	jobs.download = func(ctx context.Context, request download.Request) (string, error) {
+		close(started)
+		<-ctx.Done()
+		return "", ctx.Err()
+	}
+
+	running := jobs.start(search.Result{Name: "Caravan", ArtistName: "Duke Ellington"}, "mp3", "downloads")
+	<-started
+	queued := jobs.start(search.Result{Name: "Purple Rain", ArtistName: "Prince"}, "mp3", "downloads")
+	cancel()
+
+	waitCtx, cancelWait := context.WithTimeout(context.Background(), time.Second)
+	defer cancelWait()
+	if err := jobs.wait(waitCtx); err != nil {
+		t.Fatalf("wait() returned an unexpected error: %v", err)
+	}
+	for _, id := range []string{running.ID, queued.ID} {
+		if job, _ := jobs.get(id); job.Status != JobFailed {
+			t.Errorf("stopped job %s has status %q; want %q", job.Title, job.Status, JobFailed)
+		}
+	}
+}
+
+func TestClientRateLimiterSweep(t *testing.T) {
+	limiter := newClientRateLimiter(60)
+	now := time.Now()
+
+	limiter.allow("192.0.2.1", now)
+	limiter.allow("192.0.2.2", now.Add(2*time.Second))
+	if len(limiter.buckets) != 2 {
+		t.Errorf("full buckets were removed before the sweep interval; %d buckets left", len(limiter.buckets))
+	}
+	limiter.allow("192.0.2.2", now.Add(limiterSweepInterval+time.Second))
+	if _, ok := limiter.buckets["192.0.2.1"]; ok || len(limiter.buckets) != 1 {
+		t.Errorf("the sweep kept the bucket of an idle client: %v", limiter.buckets)
+	}
+}
@@ -1,63 +1,150 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
-	"net/http"
 	"net/url"
+	"os"
 	"strings"
 
 	"github.com/atotto/clipboard"
+	"github.com/marcusziade/songlink-cli.git/pkg/songlink"
 )
 
-type SonglinkResponse struct {
-	PageURL         string          `json:"pageUrl"`
-	LinksByPlatform LinksByPlatform `json:"linksByPlatform"`
-}
+// configuredPlatforms returns the platforms selected by the -platforms flag or, failing that,
+// the config default. It returns nil if neither selects any platforms.
+func configuredPlatforms() ([]string, error) {
+	if *platformsFlag != "" {
+		return songlink.ParsePlatforms(*platformsFlag)
+	}
 
-type LinksByPlatform struct {
-	Spotify PlatformMusic `json:"spotify"`
-}
+	config, err := LoadConfig()
+	if err != nil {
+		return nil, fmt.Errorf("error loading config: %w", err)
+	}
+	if len(config.Platforms) > 0 {
+		return songlink.ParsePlatforms(strings.Join(config.Platforms, ","))
+	}
 
-type PlatformMusic struct {
-	URL string `json:"url"`
+	return nil, nil
 }
 
-func GetLinks(searchURL string) error {
-	response, err := makeRequest(searchURL)
+// GetLinks resolves searchURL and prints or copies the links
+func GetLinks(ctx context.Context, searchURL string) error {
+	client, err := newSonglinkClient()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := linkContext(ctx)
+	defer cancel()
+	input, err := client.NormalizeURL(ctx, searchURL)
 	if err != nil {
 		return err
 	}
+	return outputLinks(ctx, client, searchURL, songlink.Query{URL: input.URL})
+}
 
-	platform := PlatformMusic{
-		URL: "",
+// outputLinks resolves query for the configured country and writes the result in the selected format.
+// inputLabel is reported as the input URL.
+func outputLinks(ctx context.Context, client *songlink.Client, inputLabel string, query songlink.Query) error {
+	format, err := ParseOutputFormat(*outputFlag)
+	if err != nil {
+		return err
+	}
+	platforms, err := configuredPlatforms()
+	if err != nil {
+		return err
 	}
-	links := LinksByPlatform{
-		Spotify: platform,
+	preference, err := configuredPreference()
+	if err != nil {
+		return err
 	}
-	linksResponse := SonglinkResponse{
-		PageURL:         "",
-		LinksByPlatform: links,
+	verifyMode, err := parseVerifyMode(*verifyFlag)
+	if err != nil {
+		return err
+	}
+	var templateName, templateText string
+	if format == FormatText {
+		templateName, templateText, err = selectedTemplate()
+		if err != nil {
+			return err
+		}
 	}
 
-	decoder := json.NewDecoder(response.Body)
-	err = decoder.Decode(&linksResponse)
+	country, err := configuredCountry()
 	if err != nil {
-		return fmt.Errorf("error decoding JSON response: %w", err)
+		return err
+	}
+	keepLocale, err := keepLocale()
+	if err != nil {
+		return err
 	}
 
-	nonLocalURL := strings.ReplaceAll(linksResponse.PageURL, "/fi", "")
-	spotifyURL := linksResponse.LinksByPlatform.Spotify.URL
+	query.Country = country
+	linksResponse, err := fetchLinks(ctx, client, query)
+	if err != nil {
+		return err
+	}
+	responses, err := withAlbum(ctx, client, linksResponse, country)
+	if err != nil {
+		return err
+	}
+	for _, response := range responses {
+		verifyLinks(response, verifyMode)
+	}
 
-	var outputString string
-	if *xFlag {
-		outputString = fmt.Sprintf("%s\n%s", nonLocalURL, spotifyURL)
-	} else if *dFlag {
-		outputString = fmt.Sprintf("<%s>\n%s", nonLocalURL, spotifyURL)
-	} else if *sFlag {
-		outputString = spotifyURL
-	} else {
-		outputString = nonLocalURL
+	// Structured output goes to stdout only so that it can be piped
+	if format != FormatText {
+		results := make([]LinkResult, len(responses))
+		pageURLs := make([]string, len(responses))
+		for i, response := range responses {
+			if platforms != nil {
+				if err := checkLinks(inputLabel, selectLinks(response, platforms, preference)); err != nil {
+					return err
+				}
+			}
+			recordHistory(newHistoryEntry(inputLabel, response, keepLocale, "", ""))
+			results[i] = NewLinkResult(inputLabel, response, platforms, keepLocale)
+			pageURLs[i] = results[i].PageURL
+		}
+		if len(results) == 1 {
+			err = WriteResult(os.Stdout, format, results[0], platforms)
+		} else {
+			err = WriteResults(os.Stdout, format, results, platforms)
+		}
+		if err != nil {
+			return err
+		}
+		return writeQRCodes(os.Stderr, pageURLs)
+	}
+
+	// With -album-also the song and album outputs are joined by a newline
+	outputs := make([]string, len(responses))
+	pageURLs := make([]string, len(responses))
+	check := linksShown(platforms, templateText)
+	for i, response := range responses {
+		selection := selectLinks(response, platforms, preference)
+		if check {
+			if err := checkLinks(inputLabel, selection); err != nil {
+				return err
+			}
+		}
+		data := NewTemplateData(NewLinkResult(inputLabel, response, selection.Platforms, keepLocale), response, preference)
+		output, err := renderTemplate(templateName, templateText, data)
+		if err != nil {
+			return err
+		}
+		recordHistory(newHistoryEntry(inputLabel, response, keepLocale, templateName, output))
+		outputs[i] = output
+		pageURLs[i] = data.PageURL
+	}
+	outputString := strings.Join(outputs, "\n")
+
+	if *noCopyFlag {
+		fmt.Println(outputString)
+		return writeQRCodes(os.Stdout, pageURLs)
 	}
 
 	err = clipboard.WriteAll(outputString)
@@ -65,37 +152,139 @@ func GetLinks(searchURL string) error {
 		return fmt.Errorf("error copying output string to clipboard: %w", err)
 	}
 
+	fmt.Print("\nSuccess ✅\n")
+	for _, response := range responses {
+		if entity := response.Entity(); entity != nil {
+			fmt.Println(entity)
+		}
+	}
 	fmt.Print(
-		"\nSuccess ✅\n",
 		outputString,
 		"\nCopied to the clipboard\n\n",
 	)
 
-	return nil
+	return writeQRCodes(os.Stdout, pageURLs)
 }
 
-func makeRequest(searchURL string) (*http.Response, error) {
-	url := buildURL(searchURL)
-	response, err := http.Get(url)
+// configuredCountry returns the country from the -country flag or the config
+func configuredCountry() (string, error) {
+	if *countryFlag != "" {
+		return songlink.ParseCountry(*countryFlag)
+	}
+
+	config, err := LoadConfig()
 	if err != nil {
-		return nil, fmt.Errorf("error making HTTP request: %w", err)
+		return "", fmt.Errorf("error loading config: %w", err)
 	}
+	return songlink.ParseCountry(config.Country)
+}
+
+// keepLocale reports whether page URLs should keep their country segment
+func keepLocale() (bool, error) {
+	if *keepLocaleFlag {
+		return true, nil
+	}
+
+	config, err := LoadConfig()
+	if err != nil {
+		return false, fmt.Errorf("error loading config: %w", err)
+	}
+	return config.KeepLocale, nil
+}
+
+// linkContext returns a context for resolving one link that is canceled after the -timeout duration
+func linkContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if *timeoutFlag <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, *timeoutFlag)
+}
+
+// fetchLinks returns the song.link response for query, from the cache when possible.
+// -no-cache bypasses the cache entirely and -refresh skips reading it.
+func fetchLinks(ctx context.Context, client *songlink.Client, query songlink.Query) (*songlink.Response, error) {
+	cache, err := getLinkCache()
+	if err != nil {
+		return nil, err
+	}
+	if *noCacheFlag {
+		cache = nil
+	}
+
+	if cache != nil && !*refreshFlag {
+		if cached, ok := cache.Get(client.BaseURL(), query.String(), query.Country); ok {
+			return cached, nil
+		}
+	}
+
+	linksResponse, err := client.Links(ctx, query)
+	if errors.Is(err, songlink.ErrTimeout) {
+		return nil, fmt.Errorf("song.link didn't respond within %s (use -timeout to wait longer): %w", *timeoutFlag, songlink.ErrTimeout)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if cache != nil {
+		// Failing to cache only costs a request next time, so it doesn't fail the lookup
+		_ = cache.Put(client.BaseURL(), query.String(), query.Country, linksResponse)
+	}
+	return linksResponse, nil
+}
+
+// APISettings are the song.link endpoint and API key used for requests
+type APISettings struct {
+	BaseURL string
+	Key     string
+}
 
-	if response.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("received non-OK HTTP response status: %s", response.Status)
+// loadAPISettings resolves the API settings from the -api-key and -api-base-url flags,
+// the SONGLINK_API_KEY and SONGLINK_API_BASE_URL environment variables and the config, in that order
+func loadAPISettings() (APISettings, error) {
+	config, err := LoadConfig()
+	if err != nil {
+		return APISettings{}, fmt.Errorf("error loading config: %w", err)
 	}
 
-	return response, nil
+	api := APISettings{
+		BaseURL: firstNonEmpty(*apiBaseURLFlag, os.Getenv("SONGLINK_API_BASE_URL"), config.APIBaseURL, songlink.DefaultBaseURL),
+		Key:     firstNonEmpty(*apiKeyFlag, os.Getenv("SONGLINK_API_KEY"), config.APIKey),
+	}
+
+	baseURL, err := url.Parse(api.BaseURL)
+	if err != nil || (baseURL.Scheme != "http" && baseURL.Scheme != "https") || baseURL.Host == "" {
+		return APISettings{}, fmt.Errorf("invalid song.link API base URL %q", api.BaseURL)
+	}
+
+	return api, nil
+}
+
+// newSonglinkClient creates a song.link client from the configured API settings,
+// sharing the rate limiter of the other song.link requests
+func newSonglinkClient() (*songlink.Client, error) {
+	api, err := loadAPISettings()
+	if err != nil {
+		return nil, err
+	}
+	limiter, err := getSonglinkLimiter(api)
+	if err != nil {
+		return nil, err
+	}
+
+	options := []songlink.Option{songlink.WithBaseURL(api.BaseURL), songlink.WithAPIKey(api.Key)}
+	// A nil *RateLimiter must not become a non-nil Limiter
+	if limiter != nil {
+		options = append(options, songlink.WithLimiter(limiter))
+	}
+	return songlink.NewClient(options...), nil
 }
 
-func buildURL(searchURL string) string {
-	url := url.URL{
-		Scheme: "https",
-		Host:   "api.song.link",
-		Path:   "/v1-alpha.1/links",
+// firstNonEmpty returns the first of values that isn't empty
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
 	}
-	values := url.Query()
-	values.Add("url", searchURL)
-	url.RawQuery = values.Encode()
-	return url.String()
+	return ""
 }
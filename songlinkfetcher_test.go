@@ -3,55 +3,33 @@ package main
 import (
 	"encoding/json"
 	"fmt"
-	"net/http"
-	"net/http/httptest"
 	"testing"
-)
-
-func TestMakeRequest(t *testing.T) {
-	searchURL := "https://music.apple.com/fi/album/caravan/1572919347?i=1572919354"
 
-	// Mock HTTP server to return a 200 OK response with a sample JSON response body
-	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusOK)
-		fmt.Fprintln(w, `{"pageUrl": "https://song.link/fi/i/1572919354", "linksByPlatform": {"spotify": {"url": "https://open.spotify.com/track/2Xtsv7BUMrNodQWH2JPOc0"}}}`)
-	}))
-	defer server.Close()
-
-	response, err := makeRequest(searchURL)
+	"github.com/marcusziade/songlink-cli.git/pkg/songlink"
+)
 
-	// Verify that the function returns the expected results
-	if err != nil {
-		t.Errorf("makeRequest(%q) returned an unexpected error: %v", searchURL, err)
-	}
+func TestDecodeLinksByPlatform(t *testing.T) {
+	body := `{"pageUrl": "https://song.link/i/1572919354", "linksByPlatform": {
+		"spotify": {"url": "https://open.spotify.com/track/2Xtsv7BUMrNodQWH2JPOc0", "entityUniqueId": "SPOTIFY_SONG::2Xtsv7BUMrNodQWH2JPOc0"},
+		"tidal": {"url": "https://listen.tidal.com/track/186428424", "entityUniqueId": "TIDAL_SONG::186428424"},
+		"deezer": {"url": "https://www.deezer.com/track/1385212222", "entityUniqueId": "DEEZER_SONG::1385212222"}
+	}}`
 
-	if response.StatusCode != http.StatusOK {
-		t.Errorf("makeRequest(%q) returned a non-OK HTTP response status: %s", searchURL, response.Status)
+	var linksResponse songlink.Response
+	if err := json.Unmarshal([]byte(body), &linksResponse); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
 	}
 
-	// Decode the response body and verify that it contains the expected values
-	linksResponse := SonglinkResponse{}
-	decoder := json.NewDecoder(response.Body)
-	err = decoder.Decode(&linksResponse)
-	if err != nil {
-		t.Errorf("makeRequest(%q) returned an invalid JSON response body: %v", searchURL, err)
+	if len(linksResponse.LinksByPlatform) != 3 {
+		t.Fatalf("decoded %d platforms; want 3", len(linksResponse.LinksByPlatform))
 	}
 
-	if linksResponse.PageURL != "https://song.link/fi/i/1572919354" {
-		t.Errorf("makeRequest(%q) returned an unexpected page URL: %s", searchURL, linksResponse.PageURL)
+	result := NewLinkResult("https://music.apple.com/album/1572919347?i=1572919354", &linksResponse, []string{"tidal", "youtube", "spotify"}, false)
+	expected := []PlatformLink{
+		{Platform: "tidal", URL: "https://listen.tidal.com/track/186428424"},
+		{Platform: "spotify", URL: "https://open.spotify.com/track/2Xtsv7BUMrNodQWH2JPOc0"},
 	}
-
-   expectedSpotifyURL := "https://open.spotify.com/track/2Xtsv7BUMrNodQWH2JPOc0"
-   if linksResponse.LinksByPlatform.Spotify.URL != expectedSpotifyURL {
-       t.Errorf("makeRequest(%q) returned an unexpected Spotify URL: %s (want %s)", searchURL, linksResponse.LinksByPlatform.Spotify.URL, expectedSpotifyURL)
-   }
-}
-
-func TestBuildURL(t *testing.T) {
-	searchURL := "https://music.apple.com/fi/album/caravan/1572919347?i=1572919354"
-	expectedURL := "https://api.song.link/v1-alpha.1/links?url=https%3A%2F%2Fmusic.apple.com%2Ffi%2Falbum%2Fcaravan%2F1572919347%3Fi%3D1572919354"
-	actualURL := buildURL(searchURL)
-	if actualURL != expectedURL {
-		t.Errorf("buildURL(%q) = %q; want %q", searchURL, actualURL, expectedURL)
+	if fmt.Sprint(result.Links) != fmt.Sprint(expected) {
+		t.Errorf("NewLinkResult().Links = %v; want %v", result.Links, expected)
 	}
 }
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/marcusziade/songlink-cli.git/pkg/songlink"
+)
+
+// defaultTemplate is used when no template is selected by flags or config
+const defaultTemplate = "songlink"
+
+// builtinTemplates are the named output templates that ship with the CLI.
+// The -x, -d and -s flags select "twitter", "discord" and "links".
+var builtinTemplates = map[string]string{
+	"songlink": `{{.PageURL}}`,
+	"twitter":  "{{.PageURL}}{{range .Links}}\n{{.URL}}{{end}}",
+	"discord":  "<{{.PageURL}}>{{range .Links}}\n{{.URL}}{{end}}",
+	"links":    "{{range $i, $link := .Links}}{{if $i}}\n{{end}}{{$link.URL}}{{end}}",
+	"slack":    `<{{.PageURL}}|{{.Label}}>`,
+	"mastodon": "{{if .Title}}🎵 {{.Label}}\n{{end}}{{.PageURL}}",
+	"markdown": `[{{.Label}}]({{.PageURL}})`,
+	"html":     `<a href="{{html .PageURL}}">{{html .Label}}</a>`,
+	"bbcode":   `[url={{.PageURL}}]{{.Label}}[/url]`,
+}
+
+// templateLinkFields matches the template fields that show the links chosen by the platform selection
+var templateLinkFields = regexp.MustCompile(`\.(Links|Preferred)\b`)
+
+// TemplateData is the value output templates are executed with.
+// Besides the LinkResult fields it gives access to every platform in the response through Link.
+type TemplateData struct {
+	LinkResult
+	// Preferred is the link of the first platform in the preference chain that the response has a link for
+	Preferred PlatformLink
+	all       songlink.LinksByPlatform
+}
+
+// NewTemplateData wraps a result and the response it was built from for template execution.
+// preference is the platform preference chain Preferred is chosen from.
+func NewTemplateData(result LinkResult, response *songlink.Response, preference []string) TemplateData {
+	preferred, _ := preferredLink(response, preference)
+	return TemplateData{LinkResult: result, Preferred: preferred, all: response.LinksByPlatform}
+}
+
+// Link returns the URL for any platform in the response, whether or not it was selected with -platforms
+func (d TemplateData) Link(platform string) string {
+	if link, ok := d.all[platform]; ok {
+		return link.URL
+	}
+	return d.LinkResult.Link(platform)
+}
+
+// ArtworkURL returns the artwork of the resolved song or album
+func (d TemplateData) ArtworkURL() string {
+	return d.ThumbnailURL
+}
+
+// Label returns "Title — Artist", falling back to the page URL when song.link returned no metadata
+func (r LinkResult) Label() string {
+	switch {
+	case r.Title != "" && r.Artist != "":
+		return fmt.Sprintf("%s — %s", r.Title, r.Artist)
+	case r.Title != "":
+		return r.Title
+	default:
+		return r.PageURL
+	}
+}
+
+// lookupTemplate returns the template text for name. Templates in the config take precedence over built-ins.
+func lookupTemplate(name string, config *Config) (string, error) {
+	if text, ok := config.Templates[name]; ok {
+		return text, nil
+	}
+	if text, ok := builtinTemplates[name]; ok {
+		return text, nil
+	}
+	return "", fmt.Errorf("unknown template %q (available templates: %s)", name, strings.Join(templateNames(config), ", "))
+}
+
+// templateNames returns the names of all built-in and configured templates, sorted
+func templateNames(config *Config) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for name := range builtinTemplates {
+		seen[name] = true
+		names = append(names, name)
+	}
+	for name := range config.Templates {
+		if !seen[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// selectedTemplate returns the name and text of the output template.
+// Precedence: -template, -t, -x/-d/-s, the config default, then the "songlink" template.
+func selectedTemplate() (string, string, error) {
+	if *templateFlag != "" {
+		return "custom", *templateFlag, nil
+	}
+
+	config, err := LoadConfig()
+	if err != nil {
+		return "", "", fmt.Errorf("error loading config: %w", err)
+	}
+
+	name := config.DefaultTemplate
+	switch {
+	case *templateNameFlag != "":
+		name = *templateNameFlag
+	case *xFlag:
+		name = "twitter"
+	case *dFlag:
+		name = "discord"
+	case *sFlag:
+		name = "links"
+	case name == "":
+		name = defaultTemplate
+	}
+
+	text, err := lookupTemplate(name, config)
+	if err != nil {
+		return "", "", err
+	}
+	return name, text, nil
+}
+
+// renderTemplate executes the template text with data
+func renderTemplate(name, text string, data TemplateData) (string, error) {
+	tmpl, err := template.New(name).Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("error parsing template %q: %w", name, err)
+	}
+
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, data); err != nil {
+		return "", fmt.Errorf("error executing template %q: %w", name, err)
+	}
+	return sb.String(), nil
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/marcusziade/songlink-cli.git/pkg/songlink"
+)
+
+func TestBuiltinTemplates(t *testing.T) {
+	response := &songlink.Response{
+		LinksByPlatform: songlink.LinksByPlatform{
+			"spotify": {URL: "https://open.spotify.com/track/2Xtsv7BUMrNodQWH2JPOc0"},
+			"tidal":   {URL: "https://listen.tidal.com/track/186428424"},
+		},
+	}
+	data := NewTemplateData(testLinkResult(), response, defaultPlatformPreference)
+
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"songlink", "https://song.link/i/1572919354"},
+		{"twitter", "https://song.link/i/1572919354\nhttps://open.spotify.com/track/2Xtsv7BUMrNodQWH2JPOc0\nhttps://listen.tidal.com/track/186428424"},
+		{"discord", "<https://song.link/i/1572919354>\nhttps://open.spotify.com/track/2Xtsv7BUMrNodQWH2JPOc0\nhttps://listen.tidal.com/track/186428424"},
+		{"links", "https://open.spotify.com/track/2Xtsv7BUMrNodQWH2JPOc0\nhttps://listen.tidal.com/track/186428424"},
+		{"slack", "<https://song.link/i/1572919354|Caravan — Duke Ellington>"},
+		{"markdown", "[Caravan — Duke Ellington](https://song.link/i/1572919354)"},
+	}
+	for _, tt := range tests {
+		output, err := renderTemplate(tt.name, builtinTemplates[tt.name], data)
+		if err != nil {
+			t.Errorf("renderTemplate(%q) returned an unexpected error: %v", tt.name, err)
+			continue
+		}
+		if output != tt.expected {
+			t.Errorf("renderTemplate(%q) = %q; want %q", tt.name, output, tt.expected)
+		}
+	}
+}
+
+func TestLookupTemplate(t *testing.T) {
+	config := &Config{Templates: map[string]string{
+		"discord": "custom {{.PageURL}}",
+		"irc":     `{{.Label}} {{.Link "tidal"}}`,
+	}}
+
+	if text, err := lookupTemplate("discord", config); err != nil || text != "custom {{.PageURL}}" {
+		t.Errorf("lookupTemplate(%q) = %q, %v; want the config template to override the built-in", "discord", text, err)
+	}
+	if text, err := lookupTemplate("bbcode", config); err != nil || text != builtinTemplates["bbcode"] {
+		t.Errorf("lookupTemplate(%q) = %q, %v; want the built-in template", "bbcode", text, err)
+	}
+	if _, err := lookupTemplate("nope", config); err == nil {
+		t.Error("lookupTemplate should reject unknown template names")
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/marcusziade/songlink-cli.git/pkg/songlink"
+)
+
+// suspiciousConfidence is the confidence below which -verify reports a match
+const suspiciousConfidence = 0.8
+
+// Modes of the -verify flag
+const (
+	verifyAsk  = "ask"
+	verifyDrop = "drop"
+	verifyKeep = "keep"
+)
+
+// verifyModeFlag is the value of -verify. Given without a value the flag means -verify=ask,
+// so other modes must be given with "=".
+type verifyModeFlag struct {
+	mode *string
+}
+
+func (f verifyModeFlag) String() string {
+	if f.mode == nil {
+		return ""
+	}
+	return *f.mode
+}
+
+func (f verifyModeFlag) Set(value string) error {
+	switch value {
+	case "true":
+		value = verifyAsk
+	case "false":
+		value = ""
+	}
+	*f.mode = value
+	return nil
+}
+
+// IsBoolFlag lets -verify be given without a value
+func (f verifyModeFlag) IsBoolFlag() bool {
+	return true
+}
+
+// verifyModeVar defines the -verify flag and returns the mode it is set to
+func verifyModeVar(name, usage string) *string {
+	mode := new(string)
+	flag.Var(verifyModeFlag{mode}, name, usage)
+	return mode
+}
+
+// parseVerifyMode validates the -verify flag. An empty mode turns verification off.
+func parseVerifyMode(value string) (string, error) {
+	mode := strings.ToLower(strings.TrimSpace(value))
+	switch mode {
+	case "", verifyKeep, verifyDrop:
+		return mode, nil
+	case verifyAsk:
+		if info, err := os.Stdin.Stat(); err != nil || info.Mode()&os.ModeCharDevice == 0 {
+			return "", fmt.Errorf("-verify=%s needs a terminal to ask on; use -verify=%s or -verify=%s", verifyAsk, verifyDrop, verifyKeep)
+		}
+		return mode, nil
+	default:
+		return "", fmt.Errorf("invalid -verify mode %q (use %s, %s or %s)", value, verifyAsk, verifyDrop, verifyKeep)
+	}
+}
+
+// verifyLinks reports the links of response whose song doesn't look like the input's on stderr and,
+// depending on mode, removes them from the response or asks whether to keep each one
+func verifyLinks(response *songlink.Response, mode string) {
+	if mode == "" {
+		return
+	}
+
+	var suspicious []songlink.Match
+	for _, match := range response.Verify() {
+		if match.Confidence < suspiciousConfidence {
+			suspicious = append(suspicious, match)
+		}
+	}
+	if len(suspicious) == 0 {
+		return
+	}
+
+	fmt.Fprintf(os.Stderr, "\nThese links may not be %s:\n", response.Entity())
+	for _, match := range suspicious {
+		fmt.Fprintf(os.Stderr, "  %-13s %3.0f%%  %s (%s)\n", match.Platform, match.Confidence*100, match.Entity, strings.Join(match.Reasons, ", "))
+	}
+
+	for _, match := range suspicious {
+		drop := mode == verifyDrop
+		if mode == verifyAsk {
+			fmt.Fprintf(os.Stderr, "Keep the %s link? [y/N]: ", match.Platform)
+			var input string
+			fmt.Scanln(&input)
+			drop = !strings.EqualFold(strings.TrimSpace(input), "y")
+		}
+		if drop {
+			delete(response.LinksByPlatform, match.Platform)
+			fmt.Fprintf(os.Stderr, "Dropped the %s link\n", match.Platform)
+		}
+	}
+	fmt.Fprintln(os.Stderr)
+}
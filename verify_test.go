@@ -0,0 +1,60 @@
+package main
+
+import (
+	"flag"
+	"strings"
+	"testing"
+
+	"github.com/marcusziade/songlink-cli.git/pkg/songlink"
+)
+
+func TestVerifyLinksDrop(t *testing.T) {
+	response := &songlink.Response{
+		EntityUniqueID: "ITUNES_SONG::1",
+		EntitiesByUniqueID: map[string]*songlink.Entity{
+			"ITUNES_SONG::1":  {Type: "song", Title: "Caravan", ArtistName: "Duke Ellington"},
+			"SPOTIFY_SONG::2": {Type: "song", Title: "Caravan", ArtistName: "Duke Ellington & His Orchestra"},
+			"TIDAL_SONG::3":   {Type: "song", Title: "Caravan (Live at Newport)", ArtistName: "Duke Ellington"},
+		},
+		LinksByPlatform: songlink.LinksByPlatform{
+			"appleMusic": {URL: "https://music.apple.com/us/album/caravan/1?i=1", EntityUniqueID: "ITUNES_SONG::1"},
+			"spotify":    {URL: "https://open.spotify.com/track/2", EntityUniqueID: "SPOTIFY_SONG::2"},
+			"tidal":      {URL: "https://listen.tidal.com/track/3", EntityUniqueID: "TIDAL_SONG::3"},
+		},
+	}
+
+	verifyLinks(response, verifyKeep)
+	if len(response.LinksByPlatform) != 3 {
+		t.Fatalf("-verify=keep removed links: %v", response.LinksByPlatform)
+	}
+	verifyLinks(response, verifyDrop)
+	if _, ok := response.LinksByPlatform["tidal"]; ok || len(response.LinksByPlatform) != 2 {
+		t.Errorf("-verify=drop left %v; want only the live recording dropped", response.LinksByPlatform)
+	}
+
+	if _, err := parseVerifyMode("maybe"); err == nil {
+		t.Error("parseVerifyMode should reject unknown modes")
+	}
+}
+
+func TestVerifyModeFlag(t *testing.T) {
+	for _, args := range [][]string{{"-verify"}, {"-verify=ask"}, {"-verify=drop"}, {}} {
+		flags := flag.NewFlagSet("test", flag.ContinueOnError)
+		mode := new(string)
+		flags.Var(verifyModeFlag{mode}, "verify", "")
+		if err := flags.Parse(append(args, "https://open.spotify.com/track/1")); err != nil {
+			t.Errorf("%v: Parse returned an unexpected error: %v", args, err)
+			continue
+		}
+		expected := ""
+		if len(args) > 0 {
+			expected = strings.TrimPrefix(strings.TrimPrefix(args[0], "-verify"), "=")
+			if expected == "" {
+				expected = verifyAsk
+			}
+		}
+		if *mode != expected || flags.NArg() != 1 {
+			t.Errorf("%v: mode = %q with %d arguments; want %q and the URL", args, *mode, flags.NArg(), expected)
+		}
+	}
+}
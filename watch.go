@@ -0,0 +1,301 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/atotto/clipboard"
+	"github.com/marcusziade/songlink-cli.git/pkg/songlink"
+)
+
+// watchUnitName is the name of the systemd user unit written by watch -install-unit
+const watchUnitName = "songlink-watch.service"
+
+// clipboardWatcher converts music links copied to the clipboard into the output of the user's template
+type clipboardWatcher struct {
+	client       *songlink.Client
+	country      string
+	platforms    []string
+	preference   []string
+	keepLocale   bool
+	templateName string
+	templateText string
+	// ignore lists domains whose links are left alone
+	ignore []string
+	// debounce is how long the clipboard has to stay the same before a link is converted
+	debounce time.Duration
+	paused   atomic.Bool
+
+	// read and write access the clipboard. They are fields so that tests don't need a real clipboard.
+	read  func() (string, error)
+	write func(string) error
+
+	// lastSeen is the clipboard text of the previous poll and written the last text the watcher copied,
+	// which must not be converted again
+	lastSeen     string
+	written      string
+	pendingSince time.Time
+}
+
+// executeWatch handles the watch subcommand
+func executeWatch(ctx context.Context, args []string) error {
+	watchCmd := flag.NewFlagSet("watch", flag.ExitOnError)
+	intervalFlag := watchCmd.Duration("interval", time.Second, "How often to check the clipboard")
+	debounceFlag := watchCmd.Duration("debounce", 500*time.Millisecond, "How long the clipboard must stay unchanged before a link is converted")
+	ignoreFlag := watchCmd.String("ignore", "", "Comma-separated domains whose links are never converted (default: watch_ignore from the config)")
+	installUnitFlag := watchCmd.Bool("install-unit", false, "Write a systemd user unit that runs the watcher and exit")
+
+	if err := watchCmd.Parse(args); err != nil {
+		return err
+	}
+	if *intervalFlag <= 0 {
+		return errors.New("-interval must be positive")
+	}
+	if *installUnitFlag {
+		// The unit runs the watcher with the same settings
+		watchArgs := []string{"watch", "-interval", intervalFlag.String(), "-debounce", debounceFlag.String()}
+		if *ignoreFlag != "" {
+			watchArgs = append(watchArgs, "-ignore", *ignoreFlag)
+		}
+		return installWatchUnit(watchArgs)
+	}
+
+	watcher, err := newClipboardWatcher(*ignoreFlag, *debounceFlag)
+	if err != nil {
+		return err
+	}
+
+	if pauseSignal != nil {
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, pauseSignal, resumeSignal)
+		defer signal.Stop(signals)
+		go func() {
+			for sig := range signals {
+				watcher.paused.Store(sig == pauseSignal)
+				if sig == pauseSignal {
+					fmt.Println("Paused")
+				} else {
+					fmt.Println("Resumed")
+				}
+			}
+		}()
+		fmt.Printf("Watching the clipboard for music links (pid %d). Send %s to pause and %s to resume, Ctrl+C to stop.\n", os.Getpid(), pauseSignal, resumeSignal)
+	} else {
+		fmt.Println("Watching the clipboard for music links. Press Ctrl+C to stop.")
+	}
+
+	watcher.Run(ctx, *intervalFlag)
+	return nil
+}
+
+// newClipboardWatcher creates a watcher using the configured template, platforms and country
+func newClipboardWatcher(ignoreValue string, debounce time.Duration) (*clipboardWatcher, error) {
+	config, err := LoadConfig()
+	if err != nil {
+		return nil, fmt.Errorf("error loading config: %w", err)
+	}
+	ignore := config.WatchIgnore
+	if ignoreValue != "" {
+		ignore = strings.Split(ignoreValue, ",")
+	}
+
+	watcher := &clipboardWatcher{debounce: debounce, read: clipboard.ReadAll, write: clipboard.WriteAll}
+	for _, domain := range ignore {
+		if domain = strings.ToLower(strings.TrimSpace(domain)); domain != "" {
+			watcher.ignore = append(watcher.ignore, domain)
+		}
+	}
+
+	if watcher.client, err = newSonglinkClient(); err != nil {
+		return nil, err
+	}
+	if watcher.country, err = configuredCountry(); err != nil {
+		return nil, err
+	}
+	if watcher.platforms, err = configuredPlatforms(); err != nil {
+		return nil, err
+	}
+	if watcher.preference, err = configuredPreference(); err != nil {
+		return nil, err
+	}
+	if watcher.keepLocale, err = keepLocale(); err != nil {
+		return nil, err
+	}
+	if watcher.templateName, watcher.templateText, err = selectedTemplate(); err != nil {
+		return nil, err
+	}
+	return watcher, nil
+}
+
+// Run polls the clipboard every interval until ctx is done. Whatever is on the clipboard when it starts is left alone.
+func (w *clipboardWatcher) Run(ctx context.Context, interval time.Duration) {
+	if text, err := w.read(); err == nil {
+		w.lastSeen = text
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			w.poll(ctx, now)
+		}
+	}
+}
+
+// poll checks the clipboard once and converts a link that has been on it for the debounce time
+func (w *clipboardWatcher) poll(ctx context.Context, now time.Time) {
+	text, err := w.read()
+	if err != nil {
+		return
+	}
+	if text != w.lastSeen {
+		w.lastSeen = text
+		w.pendingSince = now
+	}
+	if w.pendingSince.IsZero() || now.Sub(w.pendingSince) < w.debounce {
+		return
+	}
+	w.pendingSince = time.Time{}
+
+	if w.paused.Load() || text == w.written {
+		return
+	}
+	output, ok := w.convert(ctx, text)
+	if !ok {
+		return
+	}
+	if err := w.write(output); err != nil {
+		fmt.Fprintf(os.Stderr, "error copying output string to clipboard: %v\n", err)
+		return
+	}
+	w.written = output
+	w.lastSeen = output
+	fmt.Printf("%s\n  → %s\n", strings.TrimSpace(text), strings.ReplaceAll(output, "\n", "\n    "))
+}
+
+// convert resolves text if it is a single music link that isn't ignored and renders the template for it
+func (w *clipboardWatcher) convert(ctx context.Context, text string) (string, bool) {
+	text = strings.TrimSpace(text)
+	if !isInputArg(text) || text == "-" || strings.ContainsAny(text, " \t\n") || w.ignored(text) {
+		return "", false
+	}
+
+	ctx, cancel := linkContext(ctx)
+	defer cancel()
+	input, err := w.client.NormalizeURL(ctx, text)
+	if err != nil {
+		if !errors.Is(err, songlink.ErrUnsupportedURL) {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", text, err)
+		}
+		return "", false
+	}
+	response, err := fetchLinks(ctx, w.client, songlink.Query{URL: input.URL, Country: w.country})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", text, err)
+		return "", false
+	}
+
+	selection := selectLinks(response, w.platforms, w.preference)
+	if linksShown(w.platforms, w.templateText) {
+		if err := checkLinks(text, selection); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return "", false
+		}
+	}
+	data := NewTemplateData(NewLinkResult(text, response, selection.Platforms, w.keepLocale), response, w.preference)
+	output, err := renderTemplate(w.templateName, w.templateText, data)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", text, err)
+		return "", false
+	}
+	recordHistory(newHistoryEntry(text, response, w.keepLocale, w.templateName, output))
+	return output, true
+}
+
+// ignored reports whether text links to one of the ignored domains
+func (w *clipboardWatcher) ignored(text string) bool {
+	parsed, err := url.Parse(text)
+	if err != nil || parsed.Host == "" {
+		return false
+	}
+	for _, domain := range w.ignore {
+		if songlink.HostMatches(parsed.Host, domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// installWatchUnit writes a systemd user unit that runs the watcher with args in the graphical session
+func installWatchUnit(args []string) error {
+	if runtime.GOOS != "linux" {
+		return errors.New("-install-unit writes a systemd unit and is only supported on Linux")
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to find the songlink executable: %w", err)
+	}
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return fmt.Errorf("failed to find the config directory: %w", err)
+	}
+	unitDir := filepath.Join(configDir, "systemd", "user")
+	if err := os.MkdirAll(unitDir, 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", unitDir, err)
+	}
+
+	unitPath := filepath.Join(unitDir, watchUnitName)
+	if err := os.WriteFile(unitPath, []byte(watchUnit(executable, args)), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", unitPath, err)
+	}
+
+	fmt.Printf("Wrote %s\n\n", unitPath)
+	fmt.Println("Start the watcher now and with every graphical session:")
+	fmt.Println("  systemctl --user daemon-reload")
+	fmt.Println("  systemctl --user enable --now " + watchUnitName)
+	fmt.Println("\nPause and resume it with:")
+	fmt.Println("  systemctl --user kill -s USR1 " + watchUnitName)
+	fmt.Println("  systemctl --user kill -s USR2 " + watchUnitName)
+	return nil
+}
+
+// watchUnit returns the systemd unit that runs executable with args
+func watchUnit(executable string, args []string) string {
+	command := make([]string, 0, len(args)+1)
+	for _, arg := range append([]string{executable}, args...) {
+		command = append(command, systemdQuote(arg))
+	}
+	return fmt.Sprintf(`[Unit]
+Description=Convert music links copied to the clipboard into song.link links
+PartOf=graphical-session.target
+After=graphical-session.target
+
+[Service]
+ExecStart=%s
+Restart=on-failure
+
+[Install]
+WantedBy=graphical-session.target
+`, strings.Join(command, " "))
+}
+
+// systemdQuote quotes arg as one word of a systemd command line. Besides backslashes and quotes,
+// % and $ are escaped so that systemd doesn't expand them as specifiers or environment variables.
+func systemdQuote(arg string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "%", "%%", "$", "$$")
+	return `"` + replacer.Replace(arg) + `"`
+}
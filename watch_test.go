@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/marcusziade/songlink-cli.git/pkg/songlink"
+)
+
+func TestClipboardWatcherPoll(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		fmt.Fprintln(w, `{"pageUrl": "https://song.link/s/2Xtsv7BUMrNodQWH2JPOc0", "linksByPlatform": {}}`)
+	}))
+	defer server.Close()
+
+	clipboardText := ""
+	watcher := &clipboardWatcher{
+		client:       songlink.NewClient(songlink.WithBaseURL(server.URL)),
+		templateName: defaultTemplate,
+		templateText: builtinTemplates[defaultTemplate],
+		ignore:       []string{"youtube.com"},
+		debounce:     time.Second,
+		read:         func() (string, error) { return clipboardText, nil },
+		write:        func(text string) error { clipboardText = text; return nil },
+	}
+
+	start := time.Now()
+	clipboardText = "https://open.spotify.com/track/2Xtsv7BUMrNodQWH2JPOc0?si=abc"
+	watcher.poll(context.Background(), start)
+	if requests != 0 {
+		t.Fatalf("the link was converted before the debounce time passed")
+	}
+	watcher.poll(context.Background(), start.Add(time.Second))
+	if clipboardText != "https://song.link/s/2Xtsv7BUMrNodQWH2JPOc0" {
+		t.Fatalf("clipboard = %q after the debounce time; want the song.link URL", clipboardText)
+	}
+
+	// The watcher's own output and ignored domains are left alone
+	watcher.poll(context.Background(), start.Add(3*time.Second))
+	clipboardText = "https://www.youtube.com/watch?v=dQw4w9WgXcQ"
+	watcher.poll(context.Background(), start.Add(4*time.Second))
+	watcher.poll(context.Background(), start.Add(5*time.Second))
+	if requests != 1 || clipboardText != "https://www.youtube.com/watch?v=dQw4w9WgXcQ" {
+		t.Errorf("ignored link was converted (%d requests, clipboard %q)", requests, clipboardText)
+	}
+
+	// Nothing is converted while paused
+	watcher.paused.Store(true)
+	clipboardText = "https://tidal.com/track/1"
+	watcher.poll(context.Background(), start.Add(6*time.Second))
+	watcher.poll(context.Background(), start.Add(7*time.Second))
+	if requests != 1 {
+		t.Errorf("link was converted while the watcher was paused")
+	}
+}
+
+func TestWatchUnit(t *testing.T) {
+	args := []string{"watch", "-interval", "2s", "-debounce", "500ms", "-ignore", "open.spotify.com,youtube.com"}
+	unit := watchUnit("/home/me/My Apps/100%/songlink", args)
+
+	expected := `ExecStart="/home/me/My Apps/100%%/songlink" "watch" "-interval" "2s" "-debounce" "500ms" "-ignore" "open.spotify.com,youtube.com"` + "\n"
+	if !strings.Contains(unit, expected) {
+		t.Errorf("unit doesn't contain %q:\n%s", expected, unit)
+	}
+	if !strings.Contains(unit, "\nWantedBy=graphical-session.target\n") {
+		t.Errorf("unit isn't installed for the graphical session:\n%s", unit)
+	}
+}
+
+func TestSystemdQuote(t *testing.T) {
+	tests := []struct {
+		arg      string
+		expected string
+	}{
+		{"/usr/bin/songlink", `"/usr/bin/songlink"`},
+		{`/opt/"quoted" \dir`, `"/opt/\"quoted\" \\dir"`},
+		{"/home/$USER/50%", `"/home/$$USER/50%%"`},
+	}
+	for _, tt := range tests {
+		if quoted := systemdQuote(tt.arg); quoted != tt.expected {
+			t.Errorf("systemdQuote(%q) = %s; want %s", tt.arg, quoted, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,9 @@
+package main
+
+import "os"
+
+// Windows has no user signals, so the clipboard watcher can't be paused and resumed
+var (
+	pauseSignal  os.Signal
+	resumeSignal os.Signal
+)